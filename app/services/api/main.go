@@ -2,10 +2,11 @@ package main
 
 import (
 	"github.com/hpetrov29/restapi/app/services/api/v1/cmd"
+	v1 "github.com/hpetrov29/restapi/business/web/v1"
 	"github.com/joho/godotenv"
 )
 
 func main() {
 	godotenv.Load()
-	cmd.Main(cmd.Routes())
-}
\ No newline at end of file
+	cmd.Main(v1.Mount{Version: "v1", Routes: cmd.Routes()})
+}
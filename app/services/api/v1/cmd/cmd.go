@@ -2,23 +2,46 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/mail"
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/hpetrov29/restapi/business/core/user"
 	db "github.com/hpetrov29/restapi/business/data/dbsql/mysql"
 	v1 "github.com/hpetrov29/restapi/business/web/v1"
 	"github.com/hpetrov29/restapi/business/web/v1/auth"
+	"github.com/hpetrov29/restapi/business/web/v1/debug"
+	"github.com/hpetrov29/restapi/business/web/v1/idempotency/stores/idempotencysqldb"
+	"github.com/hpetrov29/restapi/business/web/v1/metrics"
+	"github.com/hpetrov29/restapi/business/web/v1/middleware"
+	"github.com/hpetrov29/restapi/business/web/v1/ratelimit"
+	"github.com/hpetrov29/restapi/business/web/v1/ratelimit/stores/ratelimitsqldb"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
+	"github.com/hpetrov29/restapi/business/web/v1/session/stores/sessionsqldb"
+	"github.com/hpetrov29/restapi/internal/health"
 	"github.com/hpetrov29/restapi/internal/keystore"
 	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/mailer"
 	"github.com/hpetrov29/restapi/internal/web"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
-func Main(routeAdder v1.RouteAdder) {
+// Main starts the service with the routes of every mounted API version.
+func Main(mounts ...v1.Mount) {
 	var log *logger.Logger
 
 	events := logger.Events{
@@ -36,14 +59,17 @@ func Main(routeAdder v1.RouteAdder) {
 
 	ctx := context.Background()
 
-	run(ctx, log, "v1", routeAdder)
+	if err := run(ctx, log, "v1", mounts); err != nil {
+		log.Error(ctx, "startup", "ERROR", err)
+		os.Exit(1)
+	}
 }
 
-func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.RouteAdder) error {
+func run(ctx context.Context, log *logger.Logger, build string, mounts []v1.Mount) error {
 
 	// -------------------------------------------------------------------------
 	// GOMAXPROCS
-	
+
 	log.Info(ctx, "service startup", "GOMAXPROCS", runtime.GOMAXPROCS(0))
 
 	// -------------------------------------------------------------------------
@@ -51,7 +77,7 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 
 	config := struct {
 		Version struct {
-			Build string
+			Build       string
 			Description string
 		}
 		Web struct {
@@ -60,7 +86,12 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 			WriteTimeout    time.Duration `conf:"default:10s"`
 			IdleTimeout     time.Duration `conf:"default:120s"`
 			ShutdownTimeout time.Duration `conf:"default:20s"`
-			// DebugHost       string        `conf:"default:0.0.0.0:4000"`
+			ShutdownDelay   time.Duration `conf:"default:5s"`
+			LogExclude      []string      `conf:"default:/v1/liveness;/v1/readiness"`
+			SlowRequest     time.Duration `conf:"default:2s"`
+			CheckTimeout    time.Duration `conf:"default:1s"`
+			DebugHost       string        `conf:"default:0.0.0.0:4000"`
+			MaxBodySize     int64         `conf:"default:1048576"`
 		}
 		DB struct {
 			User         string `conf:"default:root"`
@@ -73,7 +104,66 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 		}
 		Auth struct {
 			KeysFolder string
-			Issuer string
+			Issuer     string
+		}
+		Password struct {
+			Algorithm      string `conf:"default:argon2id"`
+			BcryptCost     int    `conf:"default:12"`
+			Argon2Time     uint32 `conf:"default:3"`
+			Argon2Memory   uint32 `conf:"default:65536"`
+			Argon2Threads  uint8  `conf:"default:4"`
+			MinLength      int    `conf:"default:12"`
+			MaxLength      int    `conf:"default:72"`
+			RequireUpper   bool   `conf:"default:true"`
+			RequireLower   bool   `conf:"default:true"`
+			RequireDigit   bool   `conf:"default:true"`
+			RequireSymbol  bool   `conf:"default:false"`
+			HistorySize    int    `conf:"default:5"`
+			BreachedFolder string
+		}
+		Session struct {
+			CookieName string        `conf:"default:session_id"`
+			IdleTTL    time.Duration `conf:"default:30m"`
+			MaxAge     time.Duration `conf:"default:168h"`
+			Secure     bool          `conf:"default:true"`
+			SameSite   string        `conf:"default:lax"`
+			Domain     string
+		}
+		Mail struct {
+			Mode     string `conf:"default:file"`
+			Host     string
+			Port     int `conf:"default:587"`
+			Username string
+			Password string `conf:"mask"`
+			From     string `conf:"default:no-reply@localhost"`
+			Folder   string `conf:"default:zarf/mail"`
+		}
+		CORS struct {
+			AllowedOrigins   []string
+			AllowCredentials bool          `conf:"default:false"`
+			MaxAge           time.Duration `conf:"default:1h"`
+		}
+		Idempotency struct {
+			TTL time.Duration `conf:"default:24h"`
+		}
+		RateLimit struct {
+			Store string `conf:"default:memory"`
+		}
+		Tracing struct {
+			Exporter    string  `conf:"default:none"`
+			Host        string  `conf:"default:localhost:4317"`
+			ServiceName string  `conf:"default:api"`
+			Probability float64 `conf:"default:0.05"`
+		}
+		Tokens struct {
+			ResetTTL   time.Duration `conf:"default:30m"`
+			ResetURL   string
+			VerifyTTL  time.Duration `conf:"default:48h"`
+			VerifyURL  string
+			Cooldown   time.Duration `conf:"default:1m"`
+			Unverified string        `conf:"default:restrict"`
+
+			ImpersonateTTL time.Duration `conf:"default:15m"`
 		}
 	}{}
 
@@ -81,10 +171,26 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 	config.Version.Description = ""
 
 	config.Web.APIHost = "localhost:3000"
-	config.Web.ReadTimeout = time.Duration(5)*time.Second
-	config.Web.WriteTimeout = time.Duration(10)*time.Second
-	config.Web.IdleTimeout = time.Duration(120)*time.Second
-	config.Web.ShutdownTimeout = time.Duration(20)*time.Second
+	config.Web.DebugHost = "localhost:4000"
+	if host := os.Getenv("WEB_DEBUG_HOST"); host != "" {
+		config.Web.DebugHost = host
+	}
+	config.Web.ReadTimeout = time.Duration(5) * time.Second
+	config.Web.WriteTimeout = time.Duration(10) * time.Second
+	config.Web.IdleTimeout = time.Duration(120) * time.Second
+	config.Web.ShutdownTimeout = time.Duration(20) * time.Second
+	config.Web.ShutdownDelay = time.Duration(5) * time.Second
+	if delay := os.Getenv("WEB_SHUTDOWN_DELAY"); delay != "" {
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			return fmt.Errorf("parsing shutdown delay: %w", err)
+		}
+		config.Web.ShutdownDelay = d
+	}
+	config.Web.LogExclude = []string{"/v1/liveness", "/v1/readiness"}
+	config.Web.SlowRequest = time.Duration(2) * time.Second
+	config.Web.CheckTimeout = time.Duration(1) * time.Second
+	config.Web.MaxBodySize = 1 << 20
 
 	config.DB.User = os.Getenv("DB_USER")
 	config.DB.Password = os.Getenv("DB_PASSWORD")
@@ -97,6 +203,100 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 	config.Auth.KeysFolder = "zarf/keys"
 	config.Auth.Issuer = "service"
 
+	config.Password.Algorithm = "argon2id"
+	config.Password.BcryptCost = 12
+	config.Password.Argon2Time = user.DefaultArgon2idParams.Time
+	config.Password.Argon2Memory = user.DefaultArgon2idParams.Memory
+	config.Password.Argon2Threads = user.DefaultArgon2idParams.Threads
+	config.Password.MinLength = 12
+	config.Password.MaxLength = 72
+	config.Password.RequireUpper = true
+	config.Password.RequireLower = true
+	config.Password.RequireDigit = true
+	config.Password.RequireSymbol = false
+	config.Password.HistorySize = 5
+	config.Password.BreachedFolder = os.Getenv("PASSWORD_BREACHED_FOLDER")
+
+	config.Session.CookieName = "session_id"
+	config.Session.IdleTTL = time.Duration(30) * time.Minute
+	config.Session.MaxAge = time.Duration(168) * time.Hour
+	config.Session.Secure = os.Getenv("SESSION_INSECURE") == ""
+	config.Session.SameSite = "lax"
+	config.Session.Domain = os.Getenv("SESSION_DOMAIN")
+
+	config.Mail.Mode = "file"
+	if mode := os.Getenv("MAIL_MODE"); mode != "" {
+		config.Mail.Mode = mode
+	}
+	config.Mail.Host = os.Getenv("MAIL_HOST")
+	config.Mail.Port = 587
+	config.Mail.Username = os.Getenv("MAIL_USERNAME")
+	config.Mail.Password = os.Getenv("MAIL_PASSWORD")
+	config.Mail.From = "no-reply@localhost"
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		config.Mail.From = from
+	}
+	config.Mail.Folder = "zarf/mail"
+
+	// CORS stays disabled until the origins of the frontends are listed.
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		config.CORS.AllowedOrigins = strings.Split(origins, ",")
+	}
+	config.CORS.AllowCredentials = os.Getenv("CORS_ALLOW_CREDENTIALS") != ""
+	config.CORS.MaxAge = time.Duration(1) * time.Hour
+
+	config.Idempotency.TTL = time.Duration(24) * time.Hour
+
+	config.RateLimit.Store = "memory"
+	if store := os.Getenv("RATELIMIT_STORE"); store != "" {
+		config.RateLimit.Store = store
+	}
+
+	config.Tracing.Exporter = "none"
+	if exporter := os.Getenv("TRACING_EXPORTER"); exporter != "" {
+		config.Tracing.Exporter = exporter
+	}
+	config.Tracing.Host = "localhost:4317"
+	if host := os.Getenv("TRACING_HOST"); host != "" {
+		config.Tracing.Host = host
+	}
+	config.Tracing.ServiceName = "api"
+	config.Tracing.Probability = 0.05
+	if probability := os.Getenv("TRACING_PROBABILITY"); probability != "" {
+		p, err := strconv.ParseFloat(probability, 64)
+		if err != nil {
+			return fmt.Errorf("parsing tracing probability: %w", err)
+		}
+		config.Tracing.Probability = p
+	}
+
+	config.Tokens.ResetTTL = time.Duration(30) * time.Minute
+	config.Tokens.ResetURL = os.Getenv("TOKENS_RESET_URL")
+	config.Tokens.VerifyTTL = time.Duration(48) * time.Hour
+	config.Tokens.VerifyURL = os.Getenv("TOKENS_VERIFY_URL")
+	config.Tokens.Cooldown = time.Duration(1) * time.Minute
+	config.Tokens.ImpersonateTTL = time.Duration(15) * time.Minute
+	config.Tokens.Unverified = "restrict"
+	if policy := os.Getenv("TOKENS_UNVERIFIED"); policy != "" {
+		config.Tokens.Unverified = policy
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Tracing Support
+
+	log.Info(ctx, "Tracing startup", "status", "initializing tracing support", "exporter", config.Tracing.Exporter, "host", config.Tracing.Host)
+
+	traceProvider, err := startTracing(ctx, config.Tracing.ServiceName, config.Tracing.Exporter, config.Tracing.Host, config.Tracing.Probability)
+	if err != nil {
+		return fmt.Errorf("starting tracing: %w", err)
+	}
+	defer func() {
+		log.Info(ctx, "Tracing shutdown", "status", "flushing spans")
+		traceProvider.Shutdown(context.Background())
+	}()
+
+	tracer := traceProvider.Tracer("service")
+
 	// -------------------------------------------------------------------------
 	// Set up database client conneciton
 
@@ -119,10 +319,17 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 		dbClient.Close()
 	}()
 
-	err = db.StatusCheck(ctx, dbClient); if err != nil {
+	err = db.StatusCheck(ctx, dbClient)
+	if err != nil {
 		fmt.Println("error database status check: ", err)
 	}
 
+	if dbClient != nil {
+		if err := metrics.RegisterDB(dbClient.DB, config.DB.Name); err != nil {
+			return fmt.Errorf("registering db metrics: %w", err)
+		}
+	}
+
 	// -------------------------------------------------------------------------
 	// Initialize authentication support
 
@@ -134,15 +341,137 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 	}
 
 	auth, err := auth.New(auth.Config{
-		Log:       log,
-		DB:        dbClient,
-		Issuer:    config.Auth.Issuer,
-		Vault: 	   keystore,
+		Log:    log,
+		DB:     dbClient,
+		Issuer: config.Auth.Issuer,
+		Vault:  keystore,
 	})
 	if err != nil {
 		return fmt.Errorf("constructing auth: %w", err)
 	}
 
+	// -------------------------------------------------------------------------
+	// Initialize session support
+
+	log.Info(ctx, "Session startup", "status", "initializing session support", "cookie", config.Session.CookieName)
+
+	sameSite, err := parseSameSite(config.Session.SameSite)
+	if err != nil {
+		return fmt.Errorf("parsing session samesite: %w", err)
+	}
+
+	sessions := session.NewManager(sessionsqldb.NewStore(log, dbClient), session.Config{
+		CookieName: config.Session.CookieName,
+		IdleTTL:    config.Session.IdleTTL,
+		MaxAge:     config.Session.MaxAge,
+		Secure:     config.Session.Secure,
+		SameSite:   sameSite,
+		Domain:     config.Session.Domain,
+	})
+
+	// -------------------------------------------------------------------------
+	// Initialize password hashing support
+
+	log.Info(ctx, "Password startup", "status", "initializing password hashing support", "algorithm", config.Password.Algorithm)
+
+	hasher, err := newPasswordHasher(config.Password.Algorithm, config.Password.BcryptCost, user.Argon2idParams{
+		Time:       config.Password.Argon2Time,
+		Memory:     config.Password.Argon2Memory,
+		Threads:    config.Password.Argon2Threads,
+		SaltLength: user.DefaultArgon2idParams.SaltLength,
+		KeyLength:  user.DefaultArgon2idParams.KeyLength,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing password hasher: %w", err)
+	}
+
+	policy := user.PasswordPolicy{
+		MinLength:            config.Password.MinLength,
+		MaxLength:            config.Password.MaxLength,
+		RequireUpper:         config.Password.RequireUpper,
+		RequireLower:         config.Password.RequireLower,
+		RequireDigit:         config.Password.RequireDigit,
+		RequireSymbol:        config.Password.RequireSymbol,
+		DisallowPersonalInfo: true,
+		HistorySize:          config.Password.HistorySize,
+	}
+
+	// The breached password corpus is large and optional, only check against
+	// it when a folder has been provided.
+	if config.Password.BreachedFolder != "" {
+		log.Info(ctx, "Password startup", "status", "loading breached password list", "folder", config.Password.BreachedFolder)
+		policy.Breached = user.NewBreachedList(os.DirFS(config.Password.BreachedFolder), 1)
+	}
+
+	unverified, err := user.ParseUnverifiedPolicy(config.Tokens.Unverified)
+	if err != nil {
+		return fmt.Errorf("parsing unverified policy: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Initialize mail support
+
+	log.Info(ctx, "Mail startup", "status", "initializing mail support", "mode", config.Mail.Mode)
+
+	mailClient, err := newMailer(config.Mail.Mode, config.Mail.Host, config.Mail.Port, config.Mail.Username, config.Mail.Password, config.Mail.From, config.Mail.Folder)
+	if err != nil {
+		return fmt.Errorf("constructing mailer: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Initialize rate limiting support
+
+	log.Info(ctx, "Rate limit startup", "status", "initializing rate limiting support", "store", config.RateLimit.Store)
+
+	var limiter ratelimit.Storer
+	switch config.RateLimit.Store {
+	case "memory":
+		limiter = ratelimit.NewMemory()
+	case "mysql":
+		limiter = ratelimitsqldb.NewStore(log, dbClient)
+	default:
+		return fmt.Errorf("unknown rate limit store %q", config.RateLimit.Store)
+	}
+
+	// -------------------------------------------------------------------------
+	// Initialize health checks
+
+	log.Info(ctx, "Health startup", "status", "initializing health checks", "timeout", config.Web.CheckTimeout)
+
+	checks := health.New(config.Web.CheckTimeout)
+
+	checks.Register("database", func(ctx context.Context) error {
+		if dbClient == nil {
+			return errors.New("database not connected")
+		}
+		return db.StatusCheck(ctx, dbClient)
+	})
+
+	checks.Register("keystore", func(ctx context.Context) error {
+		if len(keystore.KeyIDs()) == 0 {
+			return errors.New("no signing keys loaded")
+		}
+		return nil
+	})
+
+	checks.Register("opa", auth.PolicyCheck)
+
+	// -------------------------------------------------------------------------
+	// Start Debug Service
+
+	log.Info(ctx, "Debug startup", "status", "debug router started", "host", config.Web.DebugHost)
+
+	debugServer := &http.Server{
+		Addr:    config.Web.DebugHost,
+		Handler: debug.Mux(config.Version.Build),
+	}
+
+	go func() {
+		if err := debugServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error(ctx, "Debug shutdown", "status", "debug router closed", "host", config.Web.DebugHost, "msg", err)
+		}
+	}()
+
 	// -------------------------------------------------------------------------
 	// Start API
 
@@ -153,22 +482,67 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
 	muxConfig := v1.APIMuxConfig{
-		Build: build,
+		Build:    build,
 		Shutdown: shutdown,
-		Log: log,
-		Auth: auth,
-		DB: dbClient,
+		Log:      log,
+		Auth:     auth,
+		Sessions: sessions,
+		DB:       dbClient,
+		Hasher:   hasher,
+		Policy:   policy,
+		Mailer:   mailClient,
+		Tokens: v1.TokenConfig{
+			ResetTTL:  config.Tokens.ResetTTL,
+			ResetURL:  config.Tokens.ResetURL,
+			VerifyTTL: config.Tokens.VerifyTTL,
+			VerifyURL: config.Tokens.VerifyURL,
+			Cooldown:  config.Tokens.Cooldown,
+
+			ImpersonateTTL: config.Tokens.ImpersonateTTL,
+		},
+		Unverified: unverified,
+		AccessLog: middleware.LoggerConfig{
+			Exclude:       config.Web.LogExclude,
+			SlowThreshold: config.Web.SlowRequest,
+		},
+		Health:         checks,
+		Tracer:         tracer,
+		RateLimiter:    limiter,
+		Idempotency:    idempotencysqldb.NewStore(log, dbClient),
+		IdempotencyTTL: config.Idempotency.TTL,
+		MaxBodySize:    config.Web.MaxBodySize,
+		WriteTimeout:   config.Web.WriteTimeout,
+		CORS: middleware.CORSConfig{
+			AllowedOrigins: config.CORS.AllowedOrigins,
+			AllowedMethods: []string{
+				http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions,
+			},
+			AllowedHeaders: []string{
+				"Accept", "Authorization", "Content-Type", middleware.CSRFHeader, middleware.APIKeyHeader,
+				web.RequestIDHeader, web.TraceParentHeader, web.IfMatchHeader, web.IfNoneMatchHeader,
+				middleware.IdempotencyKeyHeader,
+			},
+			ExposedHeaders: []string{
+				web.RequestIDHeader, web.ETagHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+				"Idempotent-Replayed", "Content-Disposition",
+			},
+			AllowCredentials: config.CORS.AllowCredentials,
+			MaxAge:           config.CORS.MaxAge,
+		},
 	}
 
-	apiMux := v1.NewAPIMux(muxConfig, routeAdder)
+	apiMux, err := v1.NewAPIMux(muxConfig, mounts...)
+	if err != nil {
+		return fmt.Errorf("constructing api mux: %w", err)
+	}
 
 	api := &http.Server{
-        Addr:    config.Web.APIHost,
-        Handler: apiMux,
+		Addr:         config.Web.APIHost,
+		Handler:      apiMux,
 		ReadTimeout:  config.Web.ReadTimeout,
 		WriteTimeout: config.Web.WriteTimeout,
 		IdleTimeout:  config.Web.IdleTimeout,
-    }
+	}
 
 	serverErrors := make(chan error, 1)
 
@@ -176,21 +550,167 @@ func run(ctx context.Context, log *logger.Logger, build string, routeAdder v1.Ro
 		serverErrors <- api.ListenAndServe()
 	}()
 
-	select{
-		case err := <-serverErrors:
-			return fmt.Errorf("server error: %w", err)
-		case sig := <-shutdown:
-			log.Info(ctx, "API shutdown", "status", "shutdown started", "signal", sig)
-			defer log.Info(ctx, "API shutdown", "status", "shutdown complete", "signal", sig)
-	
-			ctx, cancel := context.WithTimeout(ctx, config.Web.ShutdownTimeout)
-			defer cancel()
-	
-			if err := api.Shutdown(ctx); err != nil {
-				api.Close()
-				return fmt.Errorf("could not stop server gracefully: %w", err)
-			}
+	select {
+	case err := <-serverErrors:
+		return fmt.Errorf("server error: %w", err)
+	case sig := <-shutdown:
+		log.Info(ctx, "API shutdown", "status", "shutdown started", "signal", sig)
+
+		// Fail readiness first so no new traffic is routed to the
+		// service while in flight requests drain.
+		checks.Shutdown()
+		defer log.Info(ctx, "API shutdown", "status", "shutdown complete", "signal", sig)
+
+		// Keep serving until load balancers have seen the failing probe,
+		// a second signal skips the wait.
+		log.Info(ctx, "API shutdown", "status", "draining", "delay", config.Web.ShutdownDelay)
+		select {
+		case <-time.After(config.Web.ShutdownDelay):
+		case <-shutdown:
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, config.Web.ShutdownTimeout)
+		defer cancel()
+
+		if err := api.Shutdown(ctx); err != nil {
+			api.Close()
+			debugServer.Close()
+			return fmt.Errorf("could not stop server gracefully: %w", err)
+		}
+
+		if err := debugServer.Shutdown(ctx); err != nil {
+			debugServer.Close()
+			return fmt.Errorf("could not stop debug server gracefully: %w", err)
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// newPasswordHasher constructs the password hasher for the configured
+// algorithm. Hashes produced by the other algorithm are still verified and
+// transparently migrated on the next successful login.
+func newPasswordHasher(algorithm string, bcryptCost int, params user.Argon2idParams) (user.PasswordHasher, error) {
+	switch algorithm {
+	case "bcrypt":
+		hasher, err := user.NewBcryptHasher(bcryptCost)
+		if err != nil {
+			return nil, err
+		}
+		return hasher, nil
+
+	case "argon2id":
+		hasher, err := user.NewArgon2idHasher(params)
+		if err != nil {
+			return nil, err
+		}
+		return hasher, nil
+	}
+
+	return nil, fmt.Errorf("unknown password algorithm %q", algorithm)
+}
+
+// newMailer constructs the mailer for the configured mode.
+func newMailer(mode string, host string, port int, username string, password string, from string, folder string) (mailer.Mailer, error) {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("parsing from address: %w", err)
+	}
+
+	switch mode {
+	case "smtp":
+		return mailer.NewSMTP(mailer.SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Username: username,
+			Password: password,
+			From:     *fromAddr,
+		}), nil
+
+	case "file":
+		m, err := mailer.NewFile(folder, *fromAddr)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+
+	case "memory":
+		return mailer.NewMemory(), nil
+	}
+
+	return nil, fmt.Errorf("unknown mail mode %q", mode)
+}
+
+// parseSameSite converts the configured SameSite mode of the session cookie.
+func parseSameSite(mode string) (http.SameSite, error) {
+	switch mode {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+
+	return 0, fmt.Errorf("unknown samesite mode %q", mode)
+}
+
+// startTracing configures open telemetry to be used with the service. Spans
+// are created for every request even when they aren't exported, so the trace
+// ids can be used to correlate logs.
+func startTracing(ctx context.Context, serviceName string, exporterName string, host string, probability float64) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+
+	switch exporterName {
+	case "none":
+
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("creating stdout exporter: %w", err)
+		}
+		exporter = exp
+
+	case "otlp":
+		exp, err := otlptracegrpc.New(ctx,
+			otlptracegrpc.WithInsecure(),
+			otlptracegrpc.WithEndpoint(host),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("creating otlp exporter: %w", err)
+		}
+		exporter = exp
+
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", exporterName)
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(probability))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+		)),
+	}
+
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
+			sdktrace.WithBatchTimeout(sdktrace.DefaultScheduleDelay*time.Millisecond),
+		))
+	}
+
+	traceProvider := sdktrace.NewTracerProvider(opts...)
+
+	// We must set this provider as the global provider for things to work,
+	// but we pass this provider around the program where needed to collect
+	// our traces.
+	otel.SetTracerProvider(traceProvider)
+
+	// Extract incoming trace contexts and baggage from the W3C headers.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return traceProvider, nil
+}
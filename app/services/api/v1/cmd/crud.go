@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"github.com/hpetrov29/restapi/app/services/api/v1/handlers/checkgrp"
+	"github.com/hpetrov29/restapi/app/services/api/v1/handlers/docsgrp"
 	"github.com/hpetrov29/restapi/app/services/api/v1/handlers/users"
 	v1 "github.com/hpetrov29/restapi/business/web/v1"
+	"github.com/hpetrov29/restapi/business/web/v1/response"
+	"github.com/hpetrov29/restapi/internal/openapi"
 	"github.com/hpetrov29/restapi/internal/web"
 )
 
@@ -15,10 +19,60 @@ func Routes() add {
 type add struct{}
 
 // Add implements the RouterAdder interface.
-func (add) Add(app *web.App, cfg v1.APIMuxConfig) {
-	users.Routes(app, users.Config{
-		Log:  cfg.Log,
-		Auth: cfg.Auth,
-		DB:   cfg.DB,
+func (add) Add(group *web.Group, cfg v1.APIMuxConfig) {
+	docsgrp.Routes(group, docsgrp.Config{
+		Log: cfg.Log,
+		OpenAPI: openapi.Config{
+			Title:   "Users API",
+			Version: cfg.Build,
+			Error:   response.ErrorDocument{},
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"bearerAuth": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+				},
+				"basicAuth": {
+					Type:   "http",
+					Scheme: "basic",
+				},
+				"sessionCookie": {
+					Type:        "apiKey",
+					In:          "cookie",
+					Name:        cfg.Sessions.CookieName(),
+					Description: "Unsafe requests must also send the CSRF token of the session in the X-CSRF-Token header.",
+				},
+			},
+			DefaultSecurity: []string{"bearerAuth", "sessionCookie"},
+		},
+	})
+
+	checkgrp.Routes(group, checkgrp.Config{
+		Build:  cfg.Build,
+		Log:    cfg.Log,
+		Health: cfg.Health,
+	})
+
+	users.Routes(group, users.Config{
+		Log:      cfg.Log,
+		Auth:     cfg.Auth,
+		Sessions: cfg.Sessions,
+		DB:       cfg.DB,
+		Hasher:   cfg.Hasher,
+		Policy:   cfg.Policy,
+		Mailer:   cfg.Mailer,
+		Tokens: users.TokenConfig{
+			ResetTTL:  cfg.Tokens.ResetTTL,
+			ResetURL:  cfg.Tokens.ResetURL,
+			VerifyTTL: cfg.Tokens.VerifyTTL,
+			VerifyURL: cfg.Tokens.VerifyURL,
+			Cooldown:  cfg.Tokens.Cooldown,
+
+			ImpersonateTTL: cfg.Tokens.ImpersonateTTL,
+		},
+		Unverified:     cfg.Unverified,
+		RateLimiter:    cfg.RateLimiter,
+		Idempotency:    cfg.Idempotency,
+		IdempotencyTTL: cfg.IdempotencyTTL,
 	})
 }
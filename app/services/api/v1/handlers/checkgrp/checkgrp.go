@@ -0,0 +1,83 @@
+// Package checkgrp maintains the group of handlers for health checking.
+package checkgrp
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/hpetrov29/restapi/internal/health"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// Handlers manages the set of check endpoints.
+type Handlers struct {
+	build  string
+	log    *logger.Logger
+	health *health.Registry
+}
+
+// New constructs a Handlers api for the check group.
+func New(build string, log *logger.Logger, health *health.Registry) *Handlers {
+	return &Handlers{
+		build:  build,
+		log:    log,
+		health: health,
+	}
+}
+
+// Readiness checks if the dependencies of the service are ready and if not
+// will return a 503 status. Every check is reported with its status and
+// latency, the errors of failing checks are only logged.
+func (h *Handlers) Readiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	report := h.health.Check(ctx)
+
+	statusCode := http.StatusOK
+	if !report.Ready() {
+		statusCode = http.StatusServiceUnavailable
+		h.log.Info(ctx, "readiness failure", "status", report.Status, "error", report.Error)
+
+		for _, result := range report.Checks {
+			if result.Status != health.StatusUp {
+				h.log.Info(ctx, "readiness failure", "check", result.Name, "latency", result.Latency, "ERROR", result.Error)
+			}
+		}
+	}
+
+	return web.Respond(ctx, w, report, statusCode)
+}
+
+// Liveness returns simple status info if the service is alive. If the
+// app is deployed to a Kubernetes cluster, it will also return pod, node, and
+// namespace details via the Downward API. The Kubernetes environment variables
+// need to be set within your Pod/Deployment manifest.
+func (h *Handlers) Liveness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	data := struct {
+		Status     string `json:"status,omitempty"`
+		Build      string `json:"build,omitempty"`
+		Host       string `json:"host,omitempty"`
+		Name       string `json:"name,omitempty"`
+		PodIP      string `json:"podIP,omitempty"`
+		Node       string `json:"node,omitempty"`
+		Namespace  string `json:"namespace,omitempty"`
+		GOMAXPROCS int    `json:"GOMAXPROCS,omitempty"`
+	}{
+		Status:     health.StatusUp,
+		Build:      h.build,
+		Host:       host,
+		Name:       os.Getenv("KUBERNETES_NAME"),
+		PodIP:      os.Getenv("KUBERNETES_POD_IP"),
+		Node:       os.Getenv("KUBERNETES_NODE_NAME"),
+		Namespace:  os.Getenv("KUBERNETES_NAMESPACE"),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	}
+
+	return web.Respond(ctx, w, data, http.StatusOK)
+}
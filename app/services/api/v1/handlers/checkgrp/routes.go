@@ -0,0 +1,31 @@
+package checkgrp
+
+import (
+	"net/http"
+
+	"github.com/hpetrov29/restapi/internal/health"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Build  string
+	Log    *logger.Logger
+	Health *health.Registry
+}
+
+// Routes adds specific routes for this group.
+func Routes(group *web.Group, cfg Config) {
+	handlers := New(cfg.Build, cfg.Log, cfg.Health)
+	group.Handle(http.MethodGet, "/readiness", handlers.Readiness).
+		Describe("Report whether the service and its dependencies are ready").
+		Tagged("checks").
+		Returns(http.StatusOK, health.Report{}).
+		Returns(http.StatusServiceUnavailable, health.Report{})
+
+	group.Handle(http.MethodGet, "/liveness", handlers.Liveness).
+		Describe("Report whether the service is alive").
+		Tagged("checks").
+		Returns(http.StatusOK, map[string]any{})
+}
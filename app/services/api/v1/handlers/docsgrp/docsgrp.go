@@ -0,0 +1,72 @@
+// Package docsgrp maintains the group of handlers serving the API
+// documentation.
+package docsgrp
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/openapi"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Handlers manages the set of documentation endpoints.
+type Handlers struct {
+	log    *logger.Logger
+	cfg    openapi.Config
+	routes func() []*web.Route
+
+	once sync.Once
+	spec []byte
+	err  error
+}
+
+// New constructs a Handlers api for the docs group. The specification is
+// built from the routes on first use, once every route has been added.
+func New(log *logger.Logger, cfg openapi.Config, routes func() []*web.Route) *Handlers {
+	return &Handlers{
+		log:    log,
+		cfg:    cfg,
+		routes: routes,
+	}
+}
+
+// OpenAPI returns the OpenAPI document describing the API. The document is
+// always JSON, whatever the Accept header prefers.
+func (h *Handlers) OpenAPI(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.once.Do(func() {
+		h.spec, h.err = json.Marshal(openapi.Build(h.cfg, h.routes()))
+	})
+	if h.err != nil {
+		return fmt.Errorf("openapi: %w", h.err)
+	}
+
+	return write(ctx, w, web.MediaTypeJSON, h.spec)
+}
+
+// Docs returns the page rendering the OpenAPI document for people.
+func (h *Handlers) Docs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return write(ctx, w, "text/html; charset=utf-8", docsPage)
+}
+
+// write sends a body which is already encoded.
+func write(ctx context.Context, w http.ResponseWriter, contentType string, body []byte) error {
+	web.SetStatusCode(ctx, http.StatusOK)
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package docsgrp
+
+import (
+	"net/http"
+
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/openapi"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log     *logger.Logger
+	OpenAPI openapi.Config
+}
+
+// Routes adds specific routes for this group. The document describes every
+// route of the group, including the ones added after this call.
+func Routes(group *web.Group, cfg Config) {
+	handlers := New(cfg.Log, cfg.OpenAPI, group.Routes)
+
+	group.Handle(http.MethodGet, "/openapi.json", handlers.OpenAPI).
+		Describe("OpenAPI document of the API").
+		Tagged("docs").
+		Returns(http.StatusOK, map[string]any{}, web.MediaTypeJSON)
+
+	group.Handle(http.MethodGet, "/docs", handlers.Docs).
+		Describe("Documentation of the API").
+		Tagged("docs").
+		Returns(http.StatusOK, "", "text/html")
+}
@@ -3,23 +3,26 @@ package users
 import (
 	"fmt"
 	"net/mail"
+	"net/url"
 	"time"
 
 	"github.com/hpetrov29/restapi/business/core/user"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
 	"github.com/hpetrov29/restapi/internal/validate"
 )
 
 // AppUser represents information about an individual user.
 type AppUser struct {
-	ID           string   `json:"id"`
-	Name         string   `json:"name"`
-	Email        string   `json:"email"`
-	Roles        []string `json:"roles"`
-	PasswordHash []byte   `json:"-"`
-	Department   string   `json:"department"`
-	Enabled      bool     `json:"enabled"`
-	DateCreated  string   `json:"dateCreated"`
-	DateUpdated  string   `json:"dateUpdated"`
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Email           string   `json:"email"`
+	Roles           []string `json:"roles"`
+	PasswordHash    []byte   `json:"-"`
+	Department      string   `json:"department"`
+	Enabled         bool     `json:"enabled"`
+	EmailVerifiedAt string   `json:"emailVerifiedAt,omitempty"`
+	DateCreated     string   `json:"dateCreated"`
+	DateUpdated     string   `json:"dateUpdated"`
 }
 
 func toAppUser(usr user.User) AppUser {
@@ -28,19 +31,32 @@ func toAppUser(usr user.User) AppUser {
 		roles[i] = role.Name()
 	}
 
+	var verifiedAt string
+	if usr.EmailVerified() {
+		verifiedAt = usr.EmailVerifiedAt.Format(time.RFC3339)
+	}
+
 	return AppUser{
-		ID:           usr.ID.String(),
-		Name:         usr.Name,
-		Email:        usr.Email.Address,
-		Roles:        roles,
-		PasswordHash: usr.PasswordHash,
-		Department:   usr.Department,
-		Enabled:      usr.Enabled,
-		DateCreated:  usr.DateCreated.Format(time.RFC3339),
-		DateUpdated:  usr.DateUpdated.Format(time.RFC3339),
+		ID:              usr.ID.String(),
+		Name:            usr.Name,
+		Email:           usr.Email.Address,
+		Roles:           roles,
+		PasswordHash:    usr.PasswordHash,
+		Department:      usr.Department,
+		Enabled:         usr.Enabled,
+		EmailVerifiedAt: verifiedAt,
+		DateCreated:     usr.DateCreated.Format(time.RFC3339),
+		DateUpdated:     usr.DateUpdated.Format(time.RFC3339),
 	}
 }
 
+// etag returns the entity tag of the user sent as the media type, which
+// changes with every update. It is strong so it can be used with If-Match,
+// so every media type the user can be sent as needs a tag of its own.
+func etag(usr user.User, mediaType string) string {
+	return fmt.Sprintf(`"%s-%d;%s"`, usr.ID, usr.Version, mediaType)
+}
+
 func toAppUsers(users []user.User) []AppUser {
 	items := make([]AppUser, len(users))
 	for i, usr := range users {
@@ -157,6 +173,110 @@ func (app AppUpdateUser) Validate() error {
 
 // =============================================================================
 
+// AppForgotPassword contains information needed to request a password reset.
+type AppForgotPassword struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppForgotPassword) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AppResetPassword contains information needed to reset a password.
+type AppResetPassword struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	PasswordConfirm string `json:"passwordConfirm" validate:"eqfield=Password"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppResetPassword) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AppVerifyEmail contains information needed to verify an email address.
+type AppVerifyEmail struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppVerifyEmail) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AppResendVerification contains information needed to request a new email
+// verification token.
+type AppResendVerification struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the data in the model is considered clean.
+func (app AppResendVerification) Validate() error {
+	if err := validate.Check(app); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// tokenLink appends the token to the base URL as a query parameter. When no
+// base URL is configured the raw token is returned.
+func tokenLink(base string, token string) string {
+	if base == "" {
+		return token
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return token
+	}
+
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// =============================================================================
+
+// AppSession represents a browser session of a user.
+type AppSession struct {
+	UserID      string   `json:"userId"`
+	Roles       []string `json:"roles"`
+	CSRFToken   string   `json:"csrfToken"`
+	DateExpires string   `json:"dateExpires"`
+}
+
+func toAppSession(sess session.Session) AppSession {
+	roles := make([]string, len(sess.Roles))
+	for i, role := range sess.Roles {
+		roles[i] = role.Name()
+	}
+
+	return AppSession{
+		UserID:      sess.UserID.String(),
+		Roles:       roles,
+		CSRFToken:   sess.CSRFToken,
+		DateExpires: sess.DateExpires.Format(time.RFC3339),
+	}
+}
+
+// =============================================================================
+
 type token struct {
 	Token string `json:"token"`
 }
@@ -165,4 +285,4 @@ func toToken(v string) token {
 	return token{
 		Token: v,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,66 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/hpetrov29/restapi/business/core/user"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+func TestAppUserHidesPasswordHash(t *testing.T) {
+	hash := []byte("$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy")
+	usr := toAppUser(user.User{PasswordHash: hash})
+
+	app := web.NewApp(make(chan os.Signal, 1), nil)
+	app.Handle(http.MethodGet, "", "/user", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, usr, http.StatusOK)
+	})
+	app.Handle(http.MethodGet, "", "/users", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, []AppUser{usr}, http.StatusOK)
+	})
+
+	mediaTypes := []string{
+		web.MediaTypeJSON,
+		web.MediaTypeCBOR,
+		web.MediaTypeMsgPack,
+		"application/x-msgpack",
+		"application/vnd.msgpack",
+		web.MediaTypeXML,
+		"text/xml",
+		web.MediaTypeCSV,
+		web.MediaTypeNDJSON,
+	}
+
+	forms := [][]byte{
+		hash,
+		[]byte(base64.StdEncoding.EncodeToString(hash)),
+		[]byte(base64.RawStdEncoding.EncodeToString(hash)),
+	}
+
+	for _, mediaType := range mediaTypes {
+		for _, path := range []string{"/user", "/users"} {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			r.Header.Set("Accept", mediaType)
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			// CSV only represents slices, the rest must be sent.
+			if w.Code != http.StatusOK && !(mediaType == web.MediaTypeCSV && path == "/user") {
+				t.Errorf("GET %s as %s: status = %d, want %d", path, mediaType, w.Code, http.StatusOK)
+			}
+
+			for _, form := range forms {
+				if bytes.Contains(w.Body.Bytes(), form) {
+					t.Errorf("GET %s as %s: body contains the password hash: %s", path, mediaType, w.Body)
+				}
+			}
+		}
+	}
+}
@@ -2,37 +2,142 @@ package users
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/hpetrov29/restapi/business/core/user"
 	"github.com/hpetrov29/restapi/business/core/user/stores/usersqldb"
 	"github.com/hpetrov29/restapi/business/web/v1/auth"
+	"github.com/hpetrov29/restapi/business/web/v1/idempotency"
 	"github.com/hpetrov29/restapi/business/web/v1/middleware"
+	"github.com/hpetrov29/restapi/business/web/v1/ratelimit"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
 	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/mailer"
 	"github.com/hpetrov29/restapi/internal/web"
 	"github.com/jmoiron/sqlx"
 )
 
 // Config contains all the mandatory systems required by handlers.
 type Config struct {
-	Log  *logger.Logger
-	Auth *auth.Auth
-	DB   *sqlx.DB
+	Log            *logger.Logger
+	Auth           *auth.Auth
+	Sessions       *session.Manager
+	DB             *sqlx.DB
+	Hasher         user.PasswordHasher
+	Policy         user.PasswordPolicy
+	Mailer         mailer.Mailer
+	Tokens         TokenConfig
+	Unverified     user.UnverifiedPolicy
+	RateLimiter    ratelimit.Storer
+	Idempotency    idempotency.Storer
+	IdempotencyTTL time.Duration
 }
 
 // Routes adds specific routes for this group.
-func Routes(app *web.App, cfg Config) {
-	const version = "v1"
+func Routes(group *web.Group, cfg Config) {
+	userCore := user.NewCore(usersqldb.NewStore(cfg.Log, cfg.DB), cfg.Log, cfg.Hasher, cfg.Policy)
 
-	userCore := user.NewCore(usersqldb.NewStore(cfg.Log, cfg.DB), cfg.Log)
+	handlers := New(cfg.Log, userCore, cfg.Auth, cfg.Sessions, cfg.Mailer, cfg.Tokens, cfg.Unverified)
 
-	handlers := New(userCore, cfg.Auth)
+	authenticated := middleware.Authenticate(cfg.Log, cfg.Auth, cfg.Sessions)
+	csrf := middleware.CSRF()
+	denyImpersonation := middleware.DenyImpersonation()
+	ruleAdminOnly := middleware.Authorize(cfg.Auth, auth.RuleAdminOnly)
+	ruleAdminOrSubject := middleware.Authorize(cfg.Auth, auth.RuleAdminOrSubject)
 
-	authenticated := middleware.Authenticate(cfg.Auth)
-	_ = middleware.Authorize(cfg.Auth, auth.RuleAdminOnly)
-	_ = middleware.Authorize(cfg.Auth, auth.RuleAdminOrSubject)
+	// Routes which can be abused without credentials are limited per client.
+	signupLimit := middleware.RateLimit(cfg.Log, cfg.RateLimiter, ratelimit.PerMinute(5), middleware.KeyByIP)
+	loginLimit := middleware.RateLimit(cfg.Log, cfg.RateLimiter, ratelimit.PerMinute(10), middleware.KeyByIP)
+	recoveryLimit := middleware.RateLimit(cfg.Log, cfg.RateLimiter, ratelimit.PerMinute(5), middleware.KeyByIP)
 
-	// arguments: METHOD, version, path, controller, ...middlewares
-	app.Handle(http.MethodPost, version, "/users", handlers.Create)
-	app.Handle(http.MethodGet, version, "/users/token/{kid}", handlers.Token)
-	app.Handle(http.MethodGet, version, "/users", handlers.Query, authenticated)
+	// Retries of a request carrying an Idempotency-Key replay the response.
+	idempotent := middleware.Idempotency(cfg.Log, cfg.Idempotency, cfg.IdempotencyTTL, middleware.KeyBySubject)
+
+	users := group.Group("/users")
+
+	// arguments: METHOD, path, controller, ...middlewares
+	users.Handle(http.MethodPost, "", handlers.Create, signupLimit, idempotent).
+		Describe("Create a user").Tagged("users").
+		Accepts(AppNewUser{}).
+		Returns(http.StatusCreated, AppUser{})
+
+	users.Handle(http.MethodGet, "/token/{kid}", handlers.Token, loginLimit).
+		Describe("Issue a token signed with the key").Tagged("auth").
+		SecuredBy("basicAuth").
+		Returns(http.StatusOK, token{})
+
+	users.Handle(http.MethodPost, "/password/forgot", web.JSON(handlers.ForgotPassword), recoveryLimit).
+		Describe("Mail a password reset token").Tagged("auth").
+		Accepts(AppForgotPassword{}).
+		Returns(http.StatusNoContent, nil)
+
+	users.Handle(http.MethodPost, "/password/reset", web.JSON(handlers.ResetPassword), recoveryLimit).
+		Describe("Reset a password with a mailed token").Tagged("auth").
+		Accepts(AppResetPassword{}).
+		Returns(http.StatusNoContent, nil)
+
+	users.Handle(http.MethodPost, "/email/verify", handlers.VerifyEmail, recoveryLimit).
+		Describe("Verify an email address with a mailed token").Tagged("auth").
+		Accepts(AppVerifyEmail{}).
+		Returns(http.StatusOK, AppUser{})
+
+	users.Handle(http.MethodPost, "/email/resend", handlers.ResendVerification, recoveryLimit).
+		Describe("Mail a new email verification token").Tagged("auth").
+		Accepts(AppResendVerification{}).
+		Returns(http.StatusNoContent, nil)
+
+	users.Handle(http.MethodPost, "/session", handlers.CreateSession, loginLimit).
+		Describe("Start a browser session").Tagged("auth").
+		SecuredBy("basicAuth").
+		Returns(http.StatusCreated, AppSession{})
+
+	// Every route below requires an authenticated caller.
+	authed := users.Group("", authenticated)
+
+	authed.Handle(http.MethodGet, "/session", web.JSON(handlers.QuerySession)).
+		Describe("Get the session of the request").Tagged("auth").
+		Requires(auth.RuleAuthenticate).
+		Returns(http.StatusOK, AppSession{})
+
+	authed.Handle(http.MethodDelete, "/session", handlers.DeleteSession, csrf).
+		Describe("End the session of the request").Tagged("auth").
+		Requires(auth.RuleAuthenticate).
+		Returns(http.StatusNoContent, nil)
+
+	authed.Handle(http.MethodGet, "", handlers.Query).
+		Describe("List users").Tagged("users").
+		Requires(auth.RuleAuthenticate)
+
+	authed.Handle(http.MethodGet, "/export", handlers.Export, ruleAdminOnly).
+		Describe("Stream every user").Tagged("users").
+		Requires(auth.RuleAdminOnly).
+		Returns(http.StatusOK, AppUser{}, web.MediaTypeNDJSON, web.MediaTypeCSV)
+
+	authed.Handle(http.MethodGet, "/{user_id}", handlers.QueryByID, ruleAdminOrSubject).
+		Describe("Get a user").Tagged("users").
+		Requires(auth.RuleAdminOrSubject).
+		Returns(http.StatusOK, AppUser{}).
+		Returns(http.StatusNotModified, nil)
+
+	authed.Handle(http.MethodPut, "/{user_id}", handlers.Update, csrf, ruleAdminOrSubject).
+		Describe("Update a user").Tagged("users").
+		Requires(auth.RuleAdminOrSubject).
+		Accepts(AppUpdateUser{}).
+		Returns(http.StatusOK, AppUser{})
+
+	authed.Handle(http.MethodPatch, "/{user_id}", handlers.Update, csrf, ruleAdminOrSubject).
+		Describe("Update some fields of a user").Tagged("users").
+		Requires(auth.RuleAdminOrSubject).
+		Accepts(AppUpdateUser{}).
+		Returns(http.StatusOK, AppUser{})
+
+	authed.Handle(http.MethodDelete, "/{user_id}", handlers.Delete, csrf, denyImpersonation, ruleAdminOrSubject).
+		Describe("Delete a user").Tagged("users").
+		Requires(auth.RuleAdminOrSubject).
+		Returns(http.StatusNoContent, nil)
+
+	authed.Handle(http.MethodPost, "/{user_id}/impersonate/{kid}", handlers.Impersonate, csrf, denyImpersonation, ruleAdminOnly).
+		Describe("Issue a token acting as the user").Tagged("users").
+		Requires(auth.RuleAdminOnly).
+		Returns(http.StatusCreated, token{})
 }
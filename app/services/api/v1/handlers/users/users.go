@@ -6,26 +6,68 @@ import (
 	"fmt"
 	"net/http"
 	"net/mail"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/hpetrov29/restapi/business/core/user"
 	"github.com/hpetrov29/restapi/business/web/v1/auth"
+	"github.com/hpetrov29/restapi/business/web/v1/middleware"
 	"github.com/hpetrov29/restapi/business/web/v1/response"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/mailer"
+	"github.com/hpetrov29/restapi/internal/validate"
 	"github.com/hpetrov29/restapi/internal/web"
 )
 
+// Set of error variables for handling user errors.
+var (
+	errNoSession          = errors.New("request is not authenticated with a session")
+	errImpersonateSelf    = errors.New("cannot impersonate yourself")
+	errImpersonateAdmin   = errors.New("cannot impersonate an administrator")
+	errImpersonateDisable = errors.New("cannot impersonate a disabled user")
+	errPreconditionNeeded = errors.New("the If-Match header is required")
+	errAdminOnlyFields    = errors.New("only an administrator can change roles or enabled")
+)
+
+// backgroundTimeout bounds the work a request leaves running once it has
+// been answered, like mailing a token.
+const backgroundTimeout = 30 * time.Second
+
+// TokenConfig contains the settings for the single use tokens mailed to users.
+type TokenConfig struct {
+	ResetTTL  time.Duration
+	ResetURL  string
+	VerifyTTL time.Duration
+	VerifyURL string
+	Cooldown  time.Duration
+
+	ImpersonateTTL time.Duration
+}
+
 // Handlers manages the set of user endpoints.
 type Handlers struct {
-	user *user.Core
-	auth *auth.Auth
+	log        *logger.Logger
+	user       *user.Core
+	auth       *auth.Auth
+	sessions   *session.Manager
+	mailer     mailer.Mailer
+	tokens     TokenConfig
+	unverified user.UnverifiedPolicy
 }
 
 // New constructs a new handlers struct for route access.
-func New(uc *user.Core, auth *auth.Auth) *Handlers {
+func New(log *logger.Logger, uc *user.Core, auth *auth.Auth, sessions *session.Manager, mailer mailer.Mailer, tokens TokenConfig, unverified user.UnverifiedPolicy) *Handlers {
 	return &Handlers{
-		user: uc,
-		auth: auth,
+		log:        log,
+		user:       uc,
+		auth:       auth,
+		sessions:   sessions,
+		mailer:     mailer,
+		tokens:     tokens,
+		unverified: unverified,
 	}
 }
 
@@ -43,15 +85,299 @@ func (h *Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Re
 
 	usr, err := h.user.Create(ctx, nc)
 	if err != nil {
-		if errors.Is(err, user.ErrUniqueEmail) {
+		switch {
+		case errors.Is(err, user.ErrUniqueEmail):
 			return response.NewError(err, http.StatusConflict)
+		case validate.IsFieldErrors(err):
+			return response.NewError(validate.GetFieldErrors(err), http.StatusBadRequest)
 		}
 		return fmt.Errorf("create: usr[%+v]: %w", usr, err)
 	}
 
+	if err := h.sendVerificationToken(ctx, usr); err != nil {
+		h.log.Info(ctx, "create: send verification token", "userID", usr.ID, "ERROR", err)
+	}
+
+	tag, err := representationETag(ctx, usr)
+	if err != nil {
+		return err
+	}
+	web.SetETag(w, tag)
+
 	return web.Respond(ctx, w, toAppUser(usr), http.StatusCreated)
 }
 
+// Update updates a user in the system.
+func (h *Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppUpdateUser
+	if err := web.Decode(r, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	uu, err := toCoreUpdateUser(app)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	// An impersonating admin may fix the profile of the user but not take
+	// over the account. Changing the email would let them do so through the
+	// password reset flow as surely as changing the password.
+	sensitive := uu.Password != nil || uu.Email != nil || uu.Roles != nil || uu.Enabled != nil
+	if sensitive && auth.GetClaims(ctx).IsImpersonated() {
+		return response.NewError(middleware.ErrImpersonating, http.StatusForbidden)
+	}
+
+	// The route is open to the user themselves, who must not be able to grant
+	// themselves roles or undo an administrator disabling them.
+	if uu.Roles != nil || uu.Enabled != nil {
+		if err := h.auth.Authorize(ctx, auth.GetClaims(ctx), userID, auth.RuleAdminOnly); err != nil {
+			return response.NewError(errAdminOnlyFields, http.StatusForbidden)
+		}
+	}
+
+	usr, err := h.queryForWrite(ctx, r, userID)
+	if err != nil {
+		return err
+	}
+
+	previous := usr
+
+	usr, err = h.user.Update(ctx, usr, uu)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUniqueEmail):
+			return response.NewError(err, http.StatusConflict)
+		case errors.Is(err, user.ErrVersionConflict):
+			return response.NewError(err, http.StatusPreconditionFailed)
+		case validate.IsFieldErrors(err):
+			return response.NewError(validate.GetFieldErrors(err), http.StatusBadRequest)
+		}
+		return fmt.Errorf("update: userID[%s]: %w", userID, err)
+	}
+
+	// Sessions carry the roles the user had when they were created and aren't
+	// checked against the user again, so they have to go once those roles or
+	// the right to them change.
+	if revokesSessions(previous, usr, uu) {
+		if err := h.sessions.RevokeUser(ctx, usr.ID); err != nil {
+			return fmt.Errorf("revokeuser: userID[%s]: %w", usr.ID, err)
+		}
+	}
+
+	if usr.Email.Address != previous.Email.Address {
+		if err := h.sendVerificationToken(ctx, usr); err != nil {
+			h.log.Info(ctx, "update: send verification token", "userID", usr.ID, "ERROR", err)
+		}
+	}
+
+	tag, err := representationETag(ctx, usr)
+	if err != nil {
+		return err
+	}
+	web.SetETag(w, tag)
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
+}
+
+// Delete removes a user. The If-Match header must carry the current ETag of
+// the user.
+func (h *Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	usr, err := h.queryForWrite(ctx, r, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.user.Delete(ctx, usr); err != nil {
+		if errors.Is(err, user.ErrVersionConflict) {
+			return response.NewError(err, http.StatusPreconditionFailed)
+		}
+		return fmt.Errorf("delete: userID[%s]: %w", userID, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// QueryByID returns a user by its ID. A 304 is returned when the If-None-Match
+// header carries the current ETag of the user.
+func (h *Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return response.NewError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	tag, err := representationETag(ctx, usr)
+	if err != nil {
+		return err
+	}
+	web.SetETag(w, tag)
+
+	if match := r.Header.Get(web.IfNoneMatchHeader); match != "" && web.MatchETag(match, tag) {
+		return web.Respond(ctx, w, nil, http.StatusNotModified)
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
+}
+
+// Export streams every user in the system as NDJSON or CSV, depending on the
+// Accept header. Users are written as they are read from the database so the
+// export runs in constant memory whatever the size of the table.
+func (h *Handlers) Export(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	mediaType := web.Negotiate(r, web.MediaTypeNDJSON, web.MediaTypeCSV)
+	if mediaType == "" {
+		return fmt.Errorf("%w: %s", web.ErrNotAcceptable, r.Header.Get("Accept"))
+	}
+
+	ext := "ndjson"
+	if mediaType == web.MediaTypeCSV {
+		ext = "csv"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, ext))
+
+	stream, err := web.NewStream[AppUser](ctx, w, mediaType, http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	var count int
+	err = h.user.Export(ctx, func(usr user.User) error {
+		if err := stream.Send(toAppUser(usr)); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+
+	// The status line is already on the wire so a failure can't be reported
+	// to the client anymore, the truncated body is all it will see.
+	if err != nil {
+		if ctx.Err() != nil {
+			h.log.Info(ctx, "export: client went away", "users", count)
+			return nil
+		}
+		h.log.Error(ctx, "export: stream interrupted", "users", count, "ERROR", err)
+		return nil
+	}
+
+	if err := stream.Flush(); err != nil {
+		h.log.Info(ctx, "export: flush", "users", count, "ERROR", err)
+	}
+
+	return nil
+}
+
+// ForgotPassword mails a password reset token to the user. The response is
+// the same whether or not the email belongs to a user, so the endpoint can't
+// be used to discover registered emails.
+func (h *Handlers) ForgotPassword(ctx context.Context, app AppForgotPassword) (web.NoContent, error) {
+	addr, err := mail.ParseAddress(app.Email)
+	if err != nil {
+		return web.NoContent{}, response.NewError(fmt.Errorf("parsing email: %w", err), http.StatusBadRequest)
+	}
+
+	// The token is issued and mailed in the background so the response takes
+	// as long whether or not the email belongs to a user.
+	h.background(ctx, func(ctx context.Context) {
+		if err := h.sendResetToken(ctx, *addr); err != nil {
+			h.log.Info(ctx, "forgot password", "email", addr.Address, "ERROR", err)
+		}
+	})
+
+	return web.NoContent{}, nil
+}
+
+// ResetPassword replaces the password of the user a reset token was issued to.
+func (h *Handlers) ResetPassword(ctx context.Context, app AppResetPassword) (web.NoContent, error) {
+	usr, err := h.user.ResetPassword(ctx, app.Token, app.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidToken):
+			return web.NoContent{}, response.NewError(user.ErrInvalidToken, http.StatusBadRequest)
+		case errors.Is(err, user.ErrVersionConflict):
+			return web.NoContent{}, response.NewError(user.ErrVersionConflict, http.StatusConflict)
+		case validate.IsFieldErrors(err):
+			return web.NoContent{}, response.NewError(validate.GetFieldErrors(err), http.StatusBadRequest)
+		}
+		return web.NoContent{}, fmt.Errorf("resetpassword: %w", err)
+	}
+
+	// Bearer tokens are revoked by the password change itself, browser
+	// sessions have to be removed.
+	if err := h.sessions.RevokeUser(ctx, usr.ID); err != nil {
+		return web.NoContent{}, fmt.Errorf("revokeuser: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.NoContent{}, nil
+}
+
+// VerifyEmail marks the email of the user a verification token was issued to
+// as verified.
+func (h *Handlers) VerifyEmail(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppVerifyEmail
+	if err := web.Decode(r, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	usr, err := h.user.VerifyEmail(ctx, app.Token)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidToken) {
+			return response.NewError(user.ErrInvalidToken, http.StatusBadRequest)
+		}
+		return fmt.Errorf("verifyemail: %w", err)
+	}
+
+	return web.Respond(ctx, w, toAppUser(usr), http.StatusOK)
+}
+
+// ResendVerification mails a new email verification token to the user. A
+// new token can only be requested once per cooldown period. Like
+// ForgotPassword, the response is the same whether or not the email belongs
+// to a user, is already verified or is cooling down.
+func (h *Handlers) ResendVerification(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var app AppResendVerification
+	if err := web.Decode(r, &app); err != nil {
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	addr, err := mail.ParseAddress(app.Email)
+	if err != nil {
+		return response.NewError(fmt.Errorf("parsing email: %w", err), http.StatusBadRequest)
+	}
+
+	h.background(ctx, func(ctx context.Context) {
+		usr, err := h.user.QueryByEmail(ctx, *addr)
+		if err != nil {
+			if !errors.Is(err, user.ErrNotFound) {
+				h.log.Info(ctx, "resend verification", "email", addr.Address, "ERROR", err)
+			}
+			return
+		}
+
+		if err := h.sendVerificationToken(ctx, usr); err != nil {
+			h.log.Info(ctx, "resend verification", "userID", usr.ID, "ERROR", err)
+		}
+	})
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
 func (h *Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	fmt.Println("acesssed")
 	w.WriteHeader(200)
@@ -64,35 +390,79 @@ func (h *Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Req
 		return errors.New("key id not provided")
 	}
 
-	email, pass, ok := r.BasicAuth()
-	if !ok {
-		return auth.NewAuthError("must provide email and password")
+	usr, roles, err := h.basicAuth(ctx, r)
+	if err != nil {
+		return err
 	}
 
-	addr, err := mail.ParseAddress(email)
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   usr.ID.String(),
+			Issuer:    "service",
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		},
+		Roles: roles,
+	}
+
+	token, err := h.auth.GenerateToken(kid, claims)
 	if err != nil {
-		return auth.NewAuthError("invalid email format")
+		return fmt.Errorf("generatetoken: %w", err)
 	}
 
-	usr, err := h.user.Authenticate(ctx, *addr, pass)
+	w.Write([]byte(token))
+	return nil
+}
+
+// Impersonate issues a short lived token for the user which carries the
+// admin making the request as the actor, so support staff can reproduce
+// issues as the user. Administrators can't be impersonated.
+func (h *Handlers) Impersonate(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	kid := web.Param(r, "kid")
+	if kid == "" {
+		return errors.New("key id not provided")
+	}
+
+	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		switch {
-		case errors.Is(err, user.ErrNotFound):
+		return response.NewError(err, http.StatusBadRequest)
+	}
+
+	actor := auth.GetClaims(ctx)
+	if actor.Subject == userID.String() {
+		return response.NewError(errImpersonateSelf, http.StatusBadRequest)
+	}
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
 			return response.NewError(err, http.StatusNotFound)
-		case errors.Is(err, user.ErrAuthenticationFailure):
-			return auth.NewAuthError(err.Error())
-		default:
-			return fmt.Errorf("authenticate: %w", err)
+		}
+		return fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	if !usr.Enabled {
+		return response.NewError(errImpersonateDisable, http.StatusBadRequest)
+	}
+
+	for _, role := range usr.Roles {
+		if role == user.RoleAdmin {
+			return response.NewError(errImpersonateAdmin, http.StatusForbidden)
 		}
 	}
 
+	now := time.Now().UTC()
 
 	claims := auth.Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   usr.ID.String(),
 			Issuer:    "service",
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(h.tokens.ImpersonateTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles: usr.Roles,
+		Actor: &auth.Actor{
+			Subject: actor.Subject,
 		},
 	}
 
@@ -101,6 +471,209 @@ func (h *Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Req
 		return fmt.Errorf("generatetoken: %w", err)
 	}
 
-	w.Write([]byte(token))
+	h.log.Info(ctx, "impersonation started", "subject", usr.ID, "actor", actor.Subject, "expires", claims.ExpiresAt.Time)
+
+	return web.Respond(ctx, w, toToken(token), http.StatusCreated)
+}
+
+// CreateSession starts a browser session for the user authenticated with
+// basic auth. The session id is only ever held in an HttpOnly cookie, the
+// returned CSRF token must be sent back on every unsafe request.
+func (h *Handlers) CreateSession(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	usr, roles, err := h.basicAuth(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	sess, err := h.sessions.Create(ctx, w, usr.ID, roles)
+	if err != nil {
+		return fmt.Errorf("create session: userID[%s]: %w", usr.ID, err)
+	}
+
+	return web.Respond(ctx, w, toAppSession(sess), http.StatusCreated)
+}
+
+// QuerySession returns the session of the request, which allows a browser
+// to recover its CSRF token after a reload.
+func (h *Handlers) QuerySession(ctx context.Context, _ struct{}) (AppSession, error) {
+	sess, ok := session.Get(ctx)
+	if !ok {
+		return AppSession{}, response.NewError(errNoSession, http.StatusBadRequest)
+	}
+
+	return toAppSession(sess), nil
+}
+
+// DeleteSession ends the session of the request.
+func (h *Handlers) DeleteSession(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := h.sessions.Destroy(ctx, w, r); err != nil {
+		return fmt.Errorf("destroy session: %w", err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// =============================================================================
+
+// queryForWrite loads the user about to be modified and checks the If-Match
+// header of the request carries its current ETag.
+func (h *Handlers) queryForWrite(ctx context.Context, r *http.Request, userID uuid.UUID) (user.User, error) {
+	match := r.Header.Get(web.IfMatchHeader)
+	if match == "" {
+		return user.User{}, response.NewError(errPreconditionNeeded, http.StatusPreconditionRequired)
+	}
+
+	usr, err := h.user.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return user.User{}, response.NewError(err, http.StatusNotFound)
+		}
+		return user.User{}, fmt.Errorf("querybyid: userID[%s]: %w", userID, err)
+	}
+
+	tag, err := representationETag(ctx, usr)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if !web.MatchStrongETag(match, tag) {
+		return user.User{}, response.NewError(user.ErrVersionConflict, http.StatusPreconditionFailed)
+	}
+
+	return usr, nil
+}
+
+// representationETag returns the ETag of the representation of the user
+// the request selects with its Accept header.
+func representationETag(ctx context.Context, usr user.User) (string, error) {
+	mediaType, err := web.ResponseType(ctx, toAppUser(usr))
+	if err != nil {
+		return "", err
+	}
+
+	return etag(usr, mediaType), nil
+}
+
+// revokesSessions reports whether the update changes anything the sessions
+// of the user were granted on: their password, roles, enabled flag or email,
+// which the roles of an unverified user depend on.
+func revokesSessions(before user.User, after user.User, uu user.UpdateUser) bool {
+	return uu.Password != nil ||
+		!slices.Equal(before.Roles, after.Roles) ||
+		before.Enabled != after.Enabled ||
+		before.Email.Address != after.Email.Address
+}
+
+// background runs fn on its own goroutine so the response doesn't wait for
+// it. The context keeps the values of the request, like the trace id, but
+// not its cancellation, and is given a timeout of its own.
+func (h *Handlers) background(ctx context.Context, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+
+	go func() {
+		defer cancel()
+		fn(ctx)
+	}()
+}
+
+// basicAuth authenticates the user with the email and password of the
+// request and returns the roles they may act with.
+func (h *Handlers) basicAuth(ctx context.Context, r *http.Request) (user.User, []user.Role, error) {
+	email, pass, ok := r.BasicAuth()
+	if !ok {
+		return user.User{}, nil, auth.NewAuthError("must provide email and password")
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return user.User{}, nil, auth.NewAuthError("invalid email format")
+	}
+
+	usr, err := h.user.Authenticate(ctx, *addr, pass)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			return user.User{}, nil, response.NewError(err, http.StatusNotFound)
+		case errors.Is(err, user.ErrAuthenticationFailure):
+			return user.User{}, nil, auth.NewAuthError(err.Error())
+		default:
+			return user.User{}, nil, fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	if !usr.Enabled {
+		return user.User{}, nil, auth.NewAuthError("user disabled")
+	}
+
+	roles, err := h.unverified.TokenRoles(usr)
+	if err != nil {
+		if errors.Is(err, user.ErrEmailNotVerified) {
+			return user.User{}, nil, response.NewError(err, http.StatusForbidden)
+		}
+		return user.User{}, nil, fmt.Errorf("tokenroles: %w", err)
+	}
+
+	return usr, roles, nil
+}
+
+func (h *Handlers) sendResetToken(ctx context.Context, email mail.Address) error {
+	usr, err := h.user.QueryByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("querybyemail: %w", err)
+	}
+
+	if !usr.Enabled {
+		return nil
+	}
+
+	token, err := h.user.IssueToken(ctx, usr, user.TokenPasswordReset, h.tokens.ResetTTL, h.tokens.Cooldown)
+	if err != nil {
+		return fmt.Errorf("issuetoken: %w", err)
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"We received a request to reset your password. Use the link below to choose a new one:\n\n"+
+		"%s\n\n"+
+		"The link expires in %s and can only be used once. If you didn't ask to reset your password you can ignore this email.\n",
+		usr.Name, tokenLink(h.tokens.ResetURL, token), h.tokens.ResetTTL)
+
+	msg := mailer.Message{
+		To:      []mail.Address{usr.Email},
+		Subject: "Reset your password",
+		Body:    body,
+	}
+
+	if err := h.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
+	return nil
+}
+
+func (h *Handlers) sendVerificationToken(ctx context.Context, usr user.User) error {
+	token, err := h.user.IssueVerificationToken(ctx, usr, h.tokens.VerifyTTL, h.tokens.Cooldown)
+	if err != nil {
+		return fmt.Errorf("issueverificationtoken: %w", err)
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"Please confirm this is your email address by following the link below:\n\n"+
+		"%s\n\n"+
+		"The link expires in %s. If you didn't create an account you can ignore this email.\n",
+		usr.Name, tokenLink(h.tokens.VerifyURL, token), h.tokens.VerifyTTL)
+
+	msg := mailer.Message{
+		To:      []mail.Address{usr.Email},
+		Subject: "Verify your email address",
+		Body:    body,
+	}
+
+	if err := h.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
 	return nil
 }
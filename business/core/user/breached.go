@@ -0,0 +1,91 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// BreachedChecker declares the behavior required to check whether a password
+// is known to have appeared in a data breach.
+type BreachedChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// BreachedList checks passwords against a locally stored breached password
+// corpus laid out in the k-anonymity range format. The SHA-1 hash of every
+// breached password is split into a 5 character prefix, which names the
+// file, and a 35 character suffix stored inside it with an occurrence count.
+// Example: /zarf/breached/21BD1.txt
+// Example line: 2D6D4B7E8F51D3C2E1C22A9A4C3B0E5B1E1:42
+type BreachedList struct {
+	fsys     fs.FS
+	minCount int
+}
+
+// NewBreachedList constructs a BreachedList rooted inside of a directory.
+// Passwords seen fewer than minCount times are not considered breached.
+// Example: user.NewBreachedList(os.DirFS("/zarf/breached/"), 1)
+func NewBreachedList(fsys fs.FS, minCount int) *BreachedList {
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	return &BreachedList{
+		fsys:     fsys,
+		minCount: minCount,
+	}
+}
+
+// IsBreached reports whether the password appears in the breached corpus.
+func (bl *BreachedList) IsBreached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := bl.fsys.Open(prefix + ".txt")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("opening range file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, found := strings.Cut(line, ":")
+		if !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+
+		// Files without counts list each breached suffix once.
+		if !found {
+			return true, nil
+		}
+
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return false, fmt.Errorf("parsing count for suffix %s: %w", candidate, err)
+		}
+
+		return n >= bl.minCount, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("reading range file: %w", err)
+	}
+
+	return false, nil
+}
@@ -9,36 +9,43 @@ import (
 
 // User represents information about an individual user.
 type User struct {
-	ID uuid.UUID
-	Name string
-	Email mail.Address
-	Roles []Role
-	PasswordHash []byte
-	Department string
-	Enabled bool
-	DateCreated time.Time
-	DateUpdated time.Time
+	ID                  uuid.UUID
+	Name                string
+	Email               mail.Address
+	Roles               []Role
+	PasswordHash        []byte
+	Department          string
+	Enabled             bool
+	DateCreated         time.Time
+	DateUpdated         time.Time
+	DatePasswordChanged time.Time
+	EmailVerifiedAt     time.Time
+	Version             int
+}
+
+// EmailVerified reports whether the user has confirmed they own their email.
+func (u User) EmailVerified() bool {
+	return !u.EmailVerifiedAt.IsZero()
 }
 
 // NewUser contains information needed to create a new user.
 type NewUser struct {
-	Name string
-	Email mail.Address
-	Roles []Role
-	Department string
-	Password string
+	Name            string
+	Email           mail.Address
+	Roles           []Role
+	Department      string
+	Password        string
 	PasswordConfirm string
 }
 
 // UpdateUser contains information needed to update a user.
 
 type UpdateUser struct {
-
-	Name *string
-	Email *mail.Address
-	Roles []Role
-	Department *string
-	Password *string
+	Name            *string
+	Email           *mail.Address
+	Roles           []Role
+	Department      *string
+	Password        *string
 	PasswordConfirm *string
-	Enabled *bool
-}
\ No newline at end of file
+	Enabled         *bool
+}
@@ -0,0 +1,273 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hpetrov29/restapi/internal/web"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Set of error variables for password hashing.
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownHashFormat   = errors.New("unknown password hash format")
+	ErrInvalidHasherConfig = errors.New("invalid password hasher configuration")
+)
+
+// PasswordHasher declares the behavior required to hash and verify user
+// passwords. Hashes are self-describing, which allows a hasher to verify
+// hashes produced by any supported algorithm and to tell when a stored hash
+// was produced with outdated parameters.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	Compare(hash []byte, password string) error
+	NeedsRehash(hash []byte) bool
+}
+
+// comparePassword verifies the password against a hash produced by any of
+// the supported algorithms.
+func comparePassword(hash []byte, password string) error {
+	switch {
+	case isBcryptHash(hash):
+		if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return ErrPasswordMismatch
+			}
+			return fmt.Errorf("bcrypt: %w", err)
+		}
+		return nil
+
+	case isArgon2idHash(hash):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return fmt.Errorf("argon2id: %w", err)
+		}
+
+		other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+
+	return ErrUnknownHashFormat
+}
+
+// hashPassword hashes the password with the configured hasher. Hashing is
+// deliberately slow, so it is traced on its own.
+func (c *Core) hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := web.AddSpan(ctx, "business.core.user.hashpassword", attribute.String("hasher", fmt.Sprintf("%T", c.hasher)))
+	defer span.End()
+
+	return c.hasher.Hash(password)
+}
+
+// verifyPassword compares the password against the hash with the configured
+// hasher. Like hashing, it is traced on its own.
+func (c *Core) verifyPassword(ctx context.Context, hash []byte, password string) error {
+	_, span := web.AddSpan(ctx, "business.core.user.verifypassword")
+	defer span.End()
+
+	return c.hasher.Compare(hash, password)
+}
+
+// =============================================================================
+
+// BcryptHasher hashes passwords using bcrypt with a configurable cost.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher constructs a bcrypt hasher for the specified cost.
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost %d out of range [%d, %d]: %w", cost, bcrypt.MinCost, bcrypt.MaxCost, ErrInvalidHasherConfig)
+	}
+
+	return &BcryptHasher{
+		cost: cost,
+	}, nil
+}
+
+// Hash generates a bcrypt hash of the password.
+func (h *BcryptHasher) Hash(password string) ([]byte, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return nil, fmt.Errorf("generatefrompassword: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Compare verifies the password against the hash.
+func (h *BcryptHasher) Compare(hash []byte, password string) error {
+	return comparePassword(hash, password)
+}
+
+// NeedsRehash reports whether the hash was produced by another algorithm or
+// with a different cost than the one configured.
+func (h *BcryptHasher) NeedsRehash(hash []byte) bool {
+	if !isBcryptHash(hash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost(hash)
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+func isBcryptHash(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) ||
+		bytes.HasPrefix(hash, []byte("$2b$")) ||
+		bytes.HasPrefix(hash, []byte("$2y$"))
+}
+
+// =============================================================================
+
+// Argon2idParams represents the tunable parameters of the argon2id algorithm.
+type Argon2idParams struct {
+	Time       uint32
+	Memory     uint32 // in KiB
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// DefaultArgon2idParams follows the second recommended option of RFC 9106.
+var DefaultArgon2idParams = Argon2idParams{
+	Time:       3,
+	Memory:     64 * 1024,
+	Threads:    4,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// Argon2idHasher hashes passwords using argon2id. Hashes are encoded in the
+// PHC string format: $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher constructs an argon2id hasher for the specified parameters.
+func NewArgon2idHasher(params Argon2idParams) (*Argon2idHasher, error) {
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return nil, fmt.Errorf("argon2id time, memory and threads must be set: %w", ErrInvalidHasherConfig)
+	}
+
+	if params.SaltLength < 8 || params.KeyLength < 16 {
+		return nil, fmt.Errorf("argon2id salt length must be >= 8 and key length >= 16: %w", ErrInvalidHasherConfig)
+	}
+
+	return &Argon2idHasher{
+		params: params,
+	}, nil
+}
+
+// Hash generates an argon2id hash of the password using a random salt.
+func (h *Argon2idHasher) Hash(password string) ([]byte, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+
+	return encodeArgon2id(p, salt, key), nil
+}
+
+// Compare verifies the password against the hash.
+func (h *Argon2idHasher) Compare(hash []byte, password string) error {
+	return comparePassword(hash, password)
+}
+
+// NeedsRehash reports whether the hash was produced by another algorithm or
+// with different parameters than the ones configured.
+func (h *Argon2idHasher) NeedsRehash(hash []byte) bool {
+	if !isArgon2idHash(hash) {
+		return true
+	}
+
+	params, salt, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	p := h.params
+	return params.Time != p.Time ||
+		params.Memory != p.Memory ||
+		params.Threads != p.Threads ||
+		params.KeyLength != p.KeyLength ||
+		uint32(len(salt)) != p.SaltLength
+}
+
+func isArgon2idHash(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$argon2id$"))
+}
+
+func encodeArgon2id(p Argon2idParams, salt []byte, key []byte) []byte {
+	enc := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Memory,
+		p.Time,
+		p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return []byte(enc)
+}
+
+func decodeArgon2id(hash []byte) (Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("parsing version: %w", err)
+	}
+
+	if version != argon2.Version {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	var p Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("parsing parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("decoding salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("decoding key: %w", err)
+	}
+
+	// An empty key would match any password and zero parameters make argon2
+	// panic, so a hash like that can't have been produced by a hasher.
+	if len(salt) == 0 || len(key) == 0 || p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+		return Argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
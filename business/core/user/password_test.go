@@ -0,0 +1,55 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDecodeArgon2id(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key := []byte("0123456789abcdef0123456789abcdef")
+	params := Argon2idParams{Time: 3, Memory: 64 * 1024, Threads: 4, SaltLength: 16, KeyLength: 32}
+
+	tests := []struct {
+		name       string
+		hash       string
+		wantErr    bool
+		wantFormat bool
+	}{
+		{"valid", string(encodeArgon2id(params, salt, key)), false, false},
+		{"empty key", "$argon2id$v=19$m=65536,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg$", true, true},
+		{"empty salt", "$argon2id$v=19$m=65536,t=3,p=4$$MDEyMzQ1Njc4OWFiY2RlZg", true, true},
+		{"zero time", "$argon2id$v=19$m=65536,t=0,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZg", true, true},
+		{"zero memory", "$argon2id$v=19$m=0,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZg", true, true},
+		{"zero threads", "$argon2id$v=19$m=65536,t=3,p=0$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZg", true, true},
+		{"too few parts", "$argon2id$v=19$m=65536,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg", true, true},
+		{"other algorithm", "$argon2i$v=19$m=65536,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZg", true, true},
+		{"other version", "$argon2id$v=16$m=65536,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZg", true, false},
+		{"bad parameters", "$argon2id$v=19$m=x,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZg", true, false},
+		{"bad salt", "$argon2id$v=19$m=65536,t=3,p=4$!!!$MDEyMzQ1Njc4OWFiY2RlZg", true, false},
+		{"bad key", "$argon2id$v=19$m=65536,t=3,p=4$MDEyMzQ1Njc4OWFiY2RlZg$!!!", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotParams, gotSalt, gotKey, err := decodeArgon2id([]byte(tt.hash))
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("decodeArgon2id(%q) error = %v, want error %v", tt.hash, err, tt.wantErr)
+			}
+			if tt.wantFormat && !errors.Is(err, ErrUnknownHashFormat) {
+				t.Errorf("decodeArgon2id(%q) error = %v, want %v", tt.hash, err, ErrUnknownHashFormat)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if gotParams != params {
+				t.Errorf("decodeArgon2id(%q) params = %+v, want %+v", tt.hash, gotParams, params)
+			}
+			if !bytes.Equal(gotSalt, salt) || !bytes.Equal(gotKey, key) {
+				t.Errorf("decodeArgon2id(%q) salt, key = %q, %q, want %q, %q", tt.hash, gotSalt, gotKey, salt, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/hpetrov29/restapi/internal/validate"
+)
+
+// PasswordPolicy represents the set of rules a password must satisfy before
+// it is accepted for a user.
+type PasswordPolicy struct {
+	MinLength            int
+	MaxLength            int
+	RequireUpper         bool
+	RequireLower         bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool
+	HistorySize          int
+	Breached             BreachedChecker
+}
+
+// checkPassword validates the password against the policy for the specified
+// user. Violations are reported as field errors on the password field.
+func (c *Core) checkPassword(ctx context.Context, password string, usr User) error {
+	p := c.policy
+
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUpper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(password, usr) {
+		violations = append(violations, "must not contain your name or email")
+	}
+
+	// Don't bother with the expensive checks when the password is already
+	// rejected.
+	if len(violations) > 0 {
+		return validate.NewFieldsError("password", errors.New(strings.Join(violations, ", ")))
+	}
+
+	if p.HistorySize > 0 && usr.ID != uuid.Nil {
+		history, err := c.storer.QueryPasswordHistory(ctx, usr.ID, p.HistorySize)
+		if err != nil {
+			return fmt.Errorf("querypasswordhistory: %w", err)
+		}
+
+		for _, hash := range history {
+			if err := c.verifyPassword(ctx, hash, password); err == nil {
+				return validate.NewFieldsError("password", fmt.Errorf("must not match any of your last %d passwords", p.HistorySize))
+			}
+		}
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(ctx, password)
+		if err != nil {
+			return fmt.Errorf("isbreached: %w", err)
+		}
+
+		if breached {
+			return validate.NewFieldsError("password", errors.New("has appeared in a data breach, choose a different one"))
+		}
+	}
+
+	return nil
+}
+
+// containsPersonalInfo reports whether the password contains the local part
+// of the user's email or any part of their name. Fragments shorter than three
+// characters are ignored to avoid false positives.
+func containsPersonalInfo(password string, usr User) bool {
+	const minFragment = 3
+
+	pwd := strings.ToLower(password)
+
+	fragments := strings.Fields(strings.ToLower(usr.Name))
+	if local, _, found := strings.Cut(strings.ToLower(usr.Email.Address), "@"); found {
+		fragments = append(fragments, local)
+	}
+
+	for _, fragment := range fragments {
+		if utf8.RuneCountInString(fragment) < minFragment {
+			continue
+		}
+
+		if strings.Contains(pwd, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
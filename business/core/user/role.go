@@ -11,12 +11,18 @@ type Role struct {
 var (
 	RoleAdmin = Role{"ADMIN"}
 	RoleUser  = Role{"USER"}
+
+	// RoleUnverified is given in place of the user's roles to users who
+	// haven't verified their email, depending on the UnverifiedPolicy. It
+	// doesn't satisfy any authorization rule.
+	RoleUnverified = Role{"UNVERIFIED"}
 )
 
 // Set of known roles.
 var roles = map[string]Role{
-	RoleAdmin.name: RoleAdmin,
-	RoleUser.name:  RoleUser,
+	RoleAdmin.name:      RoleAdmin,
+	RoleUser.name:       RoleUser,
+	RoleUnverified.name: RoleUnverified,
 }
 
 // ParseRole parses the string value and returns a role if one exists.
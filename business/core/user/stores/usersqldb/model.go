@@ -14,15 +14,18 @@ import (
 // dbUser represent the structure we need for moving data
 // between the app and the database.
 type dbUser struct {
-	ID           uuid.UUID      `db:"user_id"`
-	Name         string         `db:"name"`
-	Email        string         `db:"email"`
-	Roles        dbarray.String 		`db:"roles"`
-	PasswordHash []byte         `db:"password_hash"`
-	Enabled      bool           `db:"enabled"`
-	Department   sql.NullString `db:"department"`
-	DateCreated  time.Time      `db:"date_created"`
-	DateUpdated  time.Time      `db:"date_updated"`
+	ID                  uuid.UUID      `db:"user_id"`
+	Name                string         `db:"name"`
+	Email               string         `db:"email"`
+	Roles               dbarray.String `db:"roles"`
+	PasswordHash        []byte         `db:"password_hash"`
+	Enabled             bool           `db:"enabled"`
+	Department          sql.NullString `db:"department"`
+	DateCreated         time.Time      `db:"date_created"`
+	DateUpdated         time.Time      `db:"date_updated"`
+	DatePasswordChanged time.Time      `db:"date_password_changed"`
+	EmailVerifiedAt     sql.NullTime   `db:"email_verified_at"`
+	Version             int            `db:"version"`
 }
 
 func toDBUser(usr user.User) dbUser {
@@ -41,9 +44,15 @@ func toDBUser(usr user.User) dbUser {
 			String: usr.Department,
 			Valid:  usr.Department != "",
 		},
-		Enabled:     usr.Enabled,
-		DateCreated: usr.DateCreated.UTC(),
-		DateUpdated: usr.DateUpdated.UTC(),
+		Enabled:             usr.Enabled,
+		DateCreated:         usr.DateCreated.UTC(),
+		DateUpdated:         usr.DateUpdated.UTC(),
+		DatePasswordChanged: usr.DatePasswordChanged.UTC(),
+		EmailVerifiedAt: sql.NullTime{
+			Time:  usr.EmailVerifiedAt.UTC(),
+			Valid: !usr.EmailVerifiedAt.IsZero(),
+		},
+		Version: usr.Version,
 	}
 }
 
@@ -62,16 +71,60 @@ func toCoreUser(dbUsr dbUser) (user.User, error) {
 	}
 
 	usr := user.User{
-		ID:           dbUsr.ID,
-		Name:         dbUsr.Name,
-		Email:        addr,
-		Roles:        roles,
-		PasswordHash: dbUsr.PasswordHash,
-		Enabled:      dbUsr.Enabled,
-		Department:   dbUsr.Department.String,
-		DateCreated:  dbUsr.DateCreated.In(time.Local),
-		DateUpdated:  dbUsr.DateUpdated.In(time.Local),
+		ID:                  dbUsr.ID,
+		Name:                dbUsr.Name,
+		Email:               addr,
+		Roles:               roles,
+		PasswordHash:        dbUsr.PasswordHash,
+		Enabled:             dbUsr.Enabled,
+		Department:          dbUsr.Department.String,
+		DateCreated:         dbUsr.DateCreated.In(time.Local),
+		DateUpdated:         dbUsr.DateUpdated.In(time.Local),
+		DatePasswordChanged: dbUsr.DatePasswordChanged.In(time.Local),
+		Version:             dbUsr.Version,
+	}
+
+	if dbUsr.EmailVerifiedAt.Valid {
+		usr.EmailVerifiedAt = dbUsr.EmailVerifiedAt.Time.In(time.Local)
 	}
 
 	return usr, nil
-}
\ No newline at end of file
+}
+
+// =============================================================================
+
+// dbPasswordHistory represents a previously used password hash of a user.
+type dbPasswordHistory struct {
+	PasswordHash []byte `db:"password_hash"`
+}
+
+// =============================================================================
+
+// dbToken represents a single use token issued to a user.
+type dbToken struct {
+	Hash        []byte    `db:"token_hash"`
+	UserID      uuid.UUID `db:"user_id"`
+	Purpose     string    `db:"purpose"`
+	DateCreated time.Time `db:"date_created"`
+	DateExpires time.Time `db:"date_expires"`
+}
+
+func toDBToken(tkn user.Token) dbToken {
+	return dbToken{
+		Hash:        tkn.Hash,
+		UserID:      tkn.UserID,
+		Purpose:     tkn.Purpose,
+		DateCreated: tkn.DateCreated.UTC(),
+		DateExpires: tkn.DateExpires.UTC(),
+	}
+}
+
+func toCoreToken(dbTkn dbToken) user.Token {
+	return user.Token{
+		Hash:        dbTkn.Hash,
+		UserID:      dbTkn.UserID,
+		Purpose:     dbTkn.Purpose,
+		DateCreated: dbTkn.DateCreated.In(time.Local),
+		DateExpires: dbTkn.DateExpires.In(time.Local),
+	}
+}
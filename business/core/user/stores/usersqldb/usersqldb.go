@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"net/mail"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/hpetrov29/restapi/business/core/user"
 	db "github.com/hpetrov29/restapi/business/data/dbsql/mysql"
 	"github.com/hpetrov29/restapi/internal/logger"
@@ -31,12 +33,12 @@ func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
 func (s *Store) Create(ctx context.Context, usr user.User) (sql.Result, error) {
 	const q = `
 	INSERT INTO users
-		(user_id, name, email, password_hash, roles, enabled, department, date_created, date_updated)
+		(user_id, name, email, password_hash, roles, enabled, department, date_created, date_updated, date_password_changed, email_verified_at, version)
 	VALUES
-		(:user_id, :name, :email, :password_hash, :roles, :enabled, :department, :date_created, :date_updated)`
-	
-	res, err := db.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr)); 
-	
+		(:user_id, :name, :email, :password_hash, :roles, :enabled, :department, :date_created, :date_updated, :date_password_changed, :email_verified_at, :version)`
+
+	res, err := db.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr))
+
 	if err != nil {
 		if errors.Is(err, db.ErrDBDuplicatedEntry) {
 			return nil, fmt.Errorf("namedexeccontext: %w", user.ErrUniqueEmail)
@@ -47,24 +49,143 @@ func (s *Store) Create(ctx context.Context, usr user.User) (sql.Result, error) {
 	return res, nil
 }
 
-// Delete removes a user from the database.
+// Update replaces a user document in the database as long as the stored
+// version matches, and increments the version.
+func (s *Store) Update(ctx context.Context, usr user.User) error {
+	const q = `
+	UPDATE
+		users
+	SET 
+		name = :name,
+		email = :email,
+		roles = :roles,
+		password_hash = :password_hash,
+		department = :department,
+		enabled = :enabled,
+		date_updated = :date_updated,
+		date_password_changed = :date_password_changed,
+		email_verified_at = :email_verified_at,
+		version = version + 1
+	WHERE
+		user_id = :user_id AND
+		version = :version`
+
+	res, err := db.NamedExecContext(ctx, s.log, s.db, q, toDBUser(usr))
+	if err != nil {
+		if errors.Is(err, db.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", user.ErrUniqueEmail)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	if err := checkVersion(res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a user from the database as long as the stored version
+// matches.
 func (s *Store) Delete(ctx context.Context, usr user.User) error {
 	data := struct {
-		UserID string `db:"user_id"`
+		UserID  string `db:"user_id"`
+		Version int    `db:"version"`
 	}{
-		UserID: usr.ID.String(),
+		UserID:  usr.ID.String(),
+		Version: usr.Version,
 	}
 
 	const q = `
 	DELETE FROM
 		users
 	WHERE
-		user_id = :user_id`
+		user_id = :user_id AND
+		version = :version`
 
-	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+	res, err := db.NamedExecContext(ctx, s.log, s.db, q, data)
+	if err != nil {
 		return fmt.Errorf("namedexeccontext: %w", err)
 	}
 
+	if err := checkVersion(res); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkVersion reports a version conflict when the statement guarded by the
+// version of the user didn't affect any row.
+func checkVersion(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rowsaffected: %w", err)
+	}
+
+	if rows == 0 {
+		return user.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// QueryByID gets the specified user from the database.
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (user.User, error) {
+	data := struct {
+		ID string `db:"user_id"`
+	}{
+		ID: userID.String(),
+	}
+
+	const q = `
+	SELECT
+        user_id, name, email, password_hash, roles, enabled, department, date_created, date_updated, date_password_changed, email_verified_at, version
+	FROM
+		users
+	WHERE 
+		user_id = :user_id`
+
+	var dbUsr dbUser
+	if err := db.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, db.ErrDBNotFound) {
+			return user.User{}, fmt.Errorf("namedquerystruct: %w", user.ErrNotFound)
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	usr, err := toCoreUser(dbUsr)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	return usr, nil
+}
+
+// QueryAll streams every user in the database to fn in user id order. Rows
+// are read from the cursor one at a time so memory use doesn't grow with
+// the size of the table.
+func (s *Store) QueryAll(ctx context.Context, fn func(user.User) error) error {
+	const q = `
+	SELECT
+        user_id, name, email, password_hash, roles, enabled, department, date_created, date_updated, date_password_changed, email_verified_at, version
+	FROM
+		users
+	ORDER BY
+		user_id`
+
+	f := func(dbUsr dbUser) error {
+		usr, err := toCoreUser(dbUsr)
+		if err != nil {
+			return err
+		}
+		return fn(usr)
+	}
+
+	if err := db.NamedQueryEach(ctx, s.log, s.db, q, struct{}{}, f); err != nil {
+		return fmt.Errorf("namedqueryeach: %w", err)
+	}
+
 	return nil
 }
 
@@ -78,7 +199,7 @@ func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (user.User
 
 	const q = `
 	SELECT
-        user_id, name, email, password_hash, roles, enabled, department, date_created, date_updated
+        user_id, name, email, password_hash, roles, enabled, department, date_created, date_updated, date_password_changed, email_verified_at, version
 	FROM
 		users
 	WHERE
@@ -98,4 +219,264 @@ func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (user.User
 	}
 
 	return usr, nil
-}
\ No newline at end of file
+}
+
+// =============================================================================
+
+// QueryPasswordHistory retrieves the most recent password hashes of a user.
+func (s *Store) QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+		Limit  int    `db:"limit"`
+	}{
+		UserID: userID.String(),
+		Limit:  limit,
+	}
+
+	const q = `
+	SELECT
+		password_hash
+	FROM
+		user_password_history
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		date_created DESC
+	LIMIT :limit`
+
+	var dbHist []dbPasswordHistory
+	if err := db.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbHist); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	hashes := make([][]byte, len(dbHist))
+	for i, h := range dbHist {
+		hashes[i] = h.PasswordHash
+	}
+
+	return hashes, nil
+}
+
+// AddPasswordHistory records a password hash for a user and trims the
+// history down to the specified number of most recent entries.
+func (s *Store) AddPasswordHistory(ctx context.Context, userID uuid.UUID, hash []byte, dateCreated time.Time, keep int) error {
+	data := struct {
+		UserID       string    `db:"user_id"`
+		PasswordHash []byte    `db:"password_hash"`
+		DateCreated  time.Time `db:"date_created"`
+		Keep         int       `db:"keep"`
+	}{
+		UserID:       userID.String(),
+		PasswordHash: hash,
+		DateCreated:  dateCreated.UTC(),
+		Keep:         keep,
+	}
+
+	const ins = `
+	INSERT INTO user_password_history
+		(user_id, password_hash, date_created)
+	VALUES
+		(:user_id, :password_hash, :date_created)`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, ins, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	// MySQL doesn't allow LIMIT inside an IN subquery, hence the derived table.
+	const del = `
+	DELETE FROM
+		user_password_history
+	WHERE
+		user_id = :user_id AND
+		history_id NOT IN (
+			SELECT history_id FROM (
+				SELECT history_id
+				FROM user_password_history
+				WHERE user_id = :user_id
+				ORDER BY date_created DESC
+				LIMIT :keep
+			) AS recent
+		)`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, del, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// CreateToken inserts a new single use token into the database.
+func (s *Store) CreateToken(ctx context.Context, tkn user.Token) error {
+	const q = `
+	INSERT INTO user_tokens
+		(token_hash, user_id, purpose, date_created, date_expires)
+	VALUES
+		(:token_hash, :user_id, :purpose, :date_created, :date_expires)`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, toDBToken(tkn)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryToken gets the specified token from the database.
+func (s *Store) QueryToken(ctx context.Context, hash []byte, purpose string) (user.Token, error) {
+	data := struct {
+		Hash    []byte `db:"token_hash"`
+		Purpose string `db:"purpose"`
+	}{
+		Hash:    hash,
+		Purpose: purpose,
+	}
+
+	const q = `
+	SELECT
+		token_hash, user_id, purpose, date_created, date_expires
+	FROM
+		user_tokens
+	WHERE
+		token_hash = :token_hash AND
+		purpose = :purpose`
+
+	var dbTkn dbToken
+	if err := db.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTkn); err != nil {
+		if errors.Is(err, db.ErrDBNotFound) {
+			return user.Token{}, fmt.Errorf("namedquerystruct: %w", user.ErrInvalidToken)
+		}
+		return user.Token{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreToken(dbTkn), nil
+}
+
+// QueryLatestToken gets the most recently issued token of a user for the
+// specified purpose.
+func (s *Store) QueryLatestToken(ctx context.Context, userID uuid.UUID, purpose string) (user.Token, error) {
+	data := struct {
+		UserID  string `db:"user_id"`
+		Purpose string `db:"purpose"`
+	}{
+		UserID:  userID.String(),
+		Purpose: purpose,
+	}
+
+	const q = `
+	SELECT
+		token_hash, user_id, purpose, date_created, date_expires
+	FROM
+		user_tokens
+	WHERE
+		user_id = :user_id AND
+		purpose = :purpose
+	ORDER BY
+		date_created DESC
+	LIMIT 1`
+
+	var dbTkn dbToken
+	if err := db.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTkn); err != nil {
+		if errors.Is(err, db.ErrDBNotFound) {
+			return user.Token{}, fmt.Errorf("namedquerystruct: %w", user.ErrInvalidToken)
+		}
+		return user.Token{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreToken(dbTkn), nil
+}
+
+// UpdateWithToken consumes the token and updates the user in a single
+// transaction, so the token is only gone once the update succeeded. The
+// token row stays locked until the transaction ends, a concurrent request
+// with the same token fails instead of updating the user a second time.
+func (s *Store) UpdateWithToken(ctx context.Context, usr user.User, hash []byte) error {
+	return s.withinTran(ctx, func(s *Store) error {
+		if err := s.consumeToken(ctx, hash); err != nil {
+			return err
+		}
+
+		return s.Update(ctx, usr)
+	})
+}
+
+// withinTran runs fn with a store bound to a transaction, which is committed
+// when fn succeeds and rolled back otherwise.
+func (s *Store) withinTran(ctx context.Context, fn func(s *Store) error) error {
+	sdb, ok := s.db.(*sqlx.DB)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := sdb.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begintxx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Store{log: s.log, db: tx}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// consumeToken removes the specified token from the database. It fails when
+// the token no longer exists, so only one caller can ever consume a token.
+func (s *Store) consumeToken(ctx context.Context, hash []byte) error {
+	data := struct {
+		Hash []byte `db:"token_hash"`
+	}{
+		Hash: hash,
+	}
+
+	const q = `
+	DELETE FROM
+		user_tokens
+	WHERE
+		token_hash = :token_hash`
+
+	res, err := db.NamedExecContext(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rowsaffected: %w", err)
+	}
+
+	if n == 0 {
+		return user.ErrInvalidToken
+	}
+
+	return nil
+}
+
+// DeleteTokens removes every token issued to a user for the specified purpose.
+func (s *Store) DeleteTokens(ctx context.Context, userID uuid.UUID, purpose string) error {
+	data := struct {
+		UserID  string `db:"user_id"`
+		Purpose string `db:"purpose"`
+	}{
+		UserID:  userID.String(),
+		Purpose: purpose,
+	}
+
+	const q = `
+	DELETE FROM
+		user_tokens
+	WHERE
+		user_id = :user_id AND
+		purpose = :purpose`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of error variables for single use tokens.
+var (
+	ErrInvalidToken  = errors.New("token is invalid or has expired")
+	ErrTokenCooldown = errors.New("a token was issued too recently, try again later")
+)
+
+// Set of purposes a single use token can be issued for.
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+// Token represents a single use token issued to a user. Only the SHA-256
+// hash of the token is ever stored.
+type Token struct {
+	Hash        []byte
+	UserID      uuid.UUID
+	Purpose     string
+	DateCreated time.Time
+	DateExpires time.Time
+}
+
+// IssueToken generates a single use token for the specified purpose which
+// expires after the ttl. Any token previously issued to the user for the
+// same purpose is invalidated, unless it was issued less than cooldown ago
+// in which case ErrTokenCooldown is returned. The returned value is the only
+// copy of the raw token.
+func (c *Core) IssueToken(ctx context.Context, usr User, purpose string, ttl time.Duration, cooldown time.Duration) (string, error) {
+	if cooldown > 0 {
+		latest, err := c.storer.QueryLatestToken(ctx, usr.ID, purpose)
+		switch {
+		case err == nil:
+			if time.Since(latest.DateCreated) < cooldown {
+				return "", ErrTokenCooldown
+			}
+		case !errors.Is(err, ErrInvalidToken):
+			return "", fmt.Errorf("querylatesttoken: %w", err)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	now := time.Now()
+
+	tkn := Token{
+		Hash:        hashToken(token),
+		UserID:      usr.ID,
+		Purpose:     purpose,
+		DateCreated: now,
+		DateExpires: now.Add(ttl),
+	}
+
+	if err := c.storer.DeleteTokens(ctx, usr.ID, purpose); err != nil {
+		return "", fmt.Errorf("deletetokens: %w", err)
+	}
+
+	if err := c.storer.CreateToken(ctx, tkn); err != nil {
+		return "", fmt.Errorf("createtoken: %w", err)
+	}
+
+	return token, nil
+}
+
+// ResetPassword validates the password reset token and replaces the user's
+// password. The token is consumed together with the update, so it remains
+// valid when the new password is rejected or the update fails and the user
+// can try again.
+func (c *Core) ResetPassword(ctx context.Context, token string, password string) (User, error) {
+	hash := hashToken(token)
+	now := time.Now()
+
+	tkn, err := c.storer.QueryToken(ctx, hash, TokenPasswordReset)
+	if err != nil {
+		return User{}, fmt.Errorf("querytoken: %w", err)
+	}
+
+	if now.After(tkn.DateExpires) {
+		return User{}, fmt.Errorf("expired[%s]: %w", tkn.DateExpires, ErrInvalidToken)
+	}
+
+	usr, err := c.QueryByID(ctx, tkn.UserID)
+	if err != nil {
+		return User{}, err
+	}
+
+	if !usr.Enabled {
+		return User{}, fmt.Errorf("user disabled: %w", ErrInvalidToken)
+	}
+
+	if err := c.checkPassword(ctx, password, usr); err != nil {
+		return User{}, fmt.Errorf("checkpassword: %w", err)
+	}
+
+	passwordHash, err := c.hashPassword(ctx, password)
+	if err != nil {
+		return User{}, fmt.Errorf("hash: %w", err)
+	}
+
+	usr.PasswordHash = passwordHash
+	usr.DatePasswordChanged = now
+	usr.DateUpdated = now
+
+	// Consuming the token fails when a concurrent request got to it first,
+	// which keeps the token single use.
+	if err := c.storer.UpdateWithToken(ctx, usr, hash); err != nil {
+		return User{}, fmt.Errorf("updatewithtoken: %w", err)
+	}
+	usr.Version++
+
+	if err := c.recordPassword(ctx, usr); err != nil {
+		return User{}, err
+	}
+
+	return usr, nil
+}
+
+// hashToken produces the value stored in place of the raw token.
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -12,7 +12,6 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/hpetrov29/restapi/internal/logger"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Set of error variables for CRUD operations.
@@ -20,61 +19,158 @@ var (
 	ErrNotFound              = errors.New("user not found")
 	ErrUniqueEmail           = errors.New("email is not unique")
 	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrVersionConflict       = errors.New("user was modified by another request")
 )
 
 // =============================================================================
 
 // Storer interface declares the core behavior and is required to write and
-// retrieve data.
+// retrieve data. Update and Delete only succeed when the stored version of
+// the user matches, otherwise ErrVersionConflict is returned. Update
+// increments the stored version.
 type Storer interface {
 	Create(ctx context.Context, user User) (sql.Result, error)
+	Update(ctx context.Context, user User) error
 	Delete(ctx context.Context, user User) error
+	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
 	QueryByEmail(ctx context.Context, email mail.Address) (User, error)
+	QueryAll(ctx context.Context, fn func(User) error) error
+	QueryPasswordHistory(ctx context.Context, userID uuid.UUID, limit int) ([][]byte, error)
+	AddPasswordHistory(ctx context.Context, userID uuid.UUID, hash []byte, dateCreated time.Time, keep int) error
+	CreateToken(ctx context.Context, tkn Token) error
+	QueryToken(ctx context.Context, hash []byte, purpose string) (Token, error)
+	QueryLatestToken(ctx context.Context, userID uuid.UUID, purpose string) (Token, error)
+	UpdateWithToken(ctx context.Context, user User, hash []byte) error
+	DeleteTokens(ctx context.Context, userID uuid.UUID, purpose string) error
 }
 
 // Core manages the set of APIs for user api access
 type Core struct {
 	storer Storer
-	log *logger.Logger
+	log    *logger.Logger
+	hasher PasswordHasher
+	policy PasswordPolicy
 }
 
 // NewCore constructs a core for user api access.
-func NewCore(st Storer, log *logger.Logger) *Core {
+func NewCore(st Storer, log *logger.Logger, hasher PasswordHasher, policy PasswordPolicy) *Core {
 	return &Core{
-		storer: st, 
-		log: log,
+		storer: st,
+		log:    log,
+		hasher: hasher,
+		policy: policy,
 	}
 }
 
 // Create adds a new user to the system.
 func (c *Core) Create(ctx context.Context, newUser NewUser) (User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+	candidate := User{
+		Name:  newUser.Name,
+		Email: newUser.Email,
+	}
+
+	if err := c.checkPassword(ctx, newUser.Password, candidate); err != nil {
+		return User{}, fmt.Errorf("checkpassword: %w", err)
+	}
+
+	hash, err := c.hashPassword(ctx, newUser.Password)
 	if err != nil {
-		return User{}, fmt.Errorf("generatefrompassword: %w", err)
+		return User{}, fmt.Errorf("hash: %w", err)
 	}
 
 	now := time.Now()
 
 	usr := User{
-		ID:           uuid.New(),
-		Name:         newUser.Name,
-		Email:        newUser.Email,
-		PasswordHash: hash,
-		Roles:        newUser.Roles,
-		Department:   newUser.Department,
-		Enabled:      true,
-		DateCreated:  now,
-		DateUpdated:  now,
+		ID:                  uuid.New(),
+		Name:                newUser.Name,
+		Email:               newUser.Email,
+		PasswordHash:        hash,
+		Roles:               newUser.Roles,
+		Department:          newUser.Department,
+		Enabled:             true,
+		DateCreated:         now,
+		DateUpdated:         now,
+		DatePasswordChanged: now,
+		Version:             1,
 	}
 
 	if _, err := c.storer.Create(ctx, usr); err != nil {
 		return User{}, fmt.Errorf("create: %w", err)
 	}
 
+	if err := c.recordPassword(ctx, usr); err != nil {
+		return User{}, err
+	}
+
+	return usr, nil
+}
+
+// Update modifies information about a user. The update is rejected with
+// ErrVersionConflict when the user was modified since it was queried.
+func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error) {
+	if uu.Name != nil {
+		usr.Name = *uu.Name
+	}
+
+	// A new email address has to be verified again.
+	emailChanged := uu.Email != nil && uu.Email.Address != usr.Email.Address
+	if emailChanged {
+		usr.Email = *uu.Email
+		usr.EmailVerifiedAt = time.Time{}
+	}
+
+	if uu.Roles != nil {
+		usr.Roles = uu.Roles
+	}
+
+	if uu.Department != nil {
+		usr.Department = *uu.Department
+	}
+
+	if uu.Enabled != nil {
+		usr.Enabled = *uu.Enabled
+	}
+
+	now := time.Now()
+
+	if uu.Password != nil {
+		if err := c.checkPassword(ctx, *uu.Password, usr); err != nil {
+			return User{}, fmt.Errorf("checkpassword: %w", err)
+		}
+
+		hash, err := c.hashPassword(ctx, *uu.Password)
+		if err != nil {
+			return User{}, fmt.Errorf("hash: %w", err)
+		}
+		usr.PasswordHash = hash
+		usr.DatePasswordChanged = now
+	}
+
+	usr.DateUpdated = now
+
+	if err := c.storer.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+	usr.Version++
+
+	// Outstanding tokens for the old address must not verify the new one.
+	if emailChanged {
+		if err := c.storer.DeleteTokens(ctx, usr.ID, TokenEmailVerification); err != nil {
+			return User{}, fmt.Errorf("deletetokens: %w", err)
+		}
+	}
+
+	if uu.Password != nil {
+		if err := c.recordPassword(ctx, usr); err != nil {
+			return User{}, err
+		}
+	}
+
 	return usr, nil
 }
 
-// Delete removes a specified user.
+// Delete removes a specified user. The delete is rejected with
+// ErrVersionConflict when the user was modified since it was queried.
 func (c *Core) Delete(ctx context.Context, usr User) error {
 	if err := c.storer.Delete(ctx, usr); err != nil {
 		return fmt.Errorf("delete: %w", err)
@@ -83,6 +179,26 @@ func (c *Core) Delete(ctx context.Context, usr User) error {
 	return nil
 }
 
+// QueryByID finds the user by the specified ID.
+func (c *Core) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	user, err := c.storer.QueryByID(ctx, userID)
+	if err != nil {
+		return User{}, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return user, nil
+}
+
+// Export passes every user in the system to fn, one at a time, stopping at
+// the first error fn returns.
+func (c *Core) Export(ctx context.Context, fn func(User) error) error {
+	if err := c.storer.QueryAll(ctx, fn); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	return nil
+}
+
 // QueryByEmail finds the user by a specified user email.
 func (c *Core) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
 	user, err := c.storer.QueryByEmail(ctx, email)
@@ -93,7 +209,6 @@ func (c *Core) QueryByEmail(ctx context.Context, email mail.Address) (User, erro
 	return user, nil
 }
 
-
 // =============================================================================
 
 // Authenticate finds a user by their email and verifies their password. On
@@ -105,9 +220,54 @@ func (c *Core) Authenticate(ctx context.Context, email mail.Address, password st
 		return User{}, fmt.Errorf("query: email[%s]: %w", email, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
-		return User{}, fmt.Errorf("comparehashandpassword: %w", ErrAuthenticationFailure)
+	if err := c.verifyPassword(ctx, usr.PasswordHash, password); err != nil {
+		return User{}, fmt.Errorf("compare: %w", ErrAuthenticationFailure)
+	}
+
+	// The password is known to be correct at this point, so this is the only
+	// chance to migrate a hash produced with an outdated algorithm or
+	// parameters. A failure here must not fail the login.
+	if c.hasher.NeedsRehash(usr.PasswordHash) {
+		rehashed, err := c.rehash(ctx, usr, password)
+		if err != nil {
+			c.log.Info(ctx, "authenticate: rehash failed", "userID", usr.ID, "ERROR", err)
+			return usr, nil
+		}
+		usr = rehashed
 	}
 
 	return usr, nil
-}
\ No newline at end of file
+}
+
+// rehash replaces the stored password hash with one produced by the
+// configured hasher.
+func (c *Core) rehash(ctx context.Context, usr User, password string) (User, error) {
+	hash, err := c.hashPassword(ctx, password)
+	if err != nil {
+		return User{}, fmt.Errorf("hash: %w", err)
+	}
+
+	usr.PasswordHash = hash
+	usr.DateUpdated = time.Now()
+
+	if err := c.storer.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+	usr.Version++
+
+	return usr, nil
+}
+
+// recordPassword adds the user's current password hash to their password
+// history when the policy keeps one.
+func (c *Core) recordPassword(ctx context.Context, usr User) error {
+	if c.policy.HistorySize <= 0 {
+		return nil
+	}
+
+	if err := c.storer.AddPasswordHistory(ctx, usr.ID, usr.PasswordHash, usr.DateUpdated, c.policy.HistorySize); err != nil {
+		return fmt.Errorf("addpasswordhistory: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Set of error variables for email verification.
+var (
+	ErrEmailNotVerified     = errors.New("email is not verified")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+)
+
+// UnverifiedPolicy represents what a user with an unverified email is
+// allowed to do when requesting a token.
+type UnverifiedPolicy struct {
+	name string
+}
+
+// Set of possible policies for unverified users.
+var (
+	UnverifiedAllow    = UnverifiedPolicy{"allow"}
+	UnverifiedRestrict = UnverifiedPolicy{"restrict"}
+	UnverifiedDeny     = UnverifiedPolicy{"deny"}
+)
+
+// Set of known policies for unverified users.
+var unverifiedPolicies = map[string]UnverifiedPolicy{
+	UnverifiedAllow.name:    UnverifiedAllow,
+	UnverifiedRestrict.name: UnverifiedRestrict,
+	UnverifiedDeny.name:     UnverifiedDeny,
+}
+
+// ParseUnverifiedPolicy parses the string value and returns a policy if one
+// exists.
+func ParseUnverifiedPolicy(value string) (UnverifiedPolicy, error) {
+	policy, exists := unverifiedPolicies[value]
+	if !exists {
+		return UnverifiedPolicy{}, fmt.Errorf("invalid unverified policy %q", value)
+	}
+
+	return policy, nil
+}
+
+// Name returns the name of the policy.
+func (p UnverifiedPolicy) Name() string {
+	return p.name
+}
+
+// TokenRoles returns the roles a token issued to the user should carry under
+// the policy. The zero value policy behaves like UnverifiedAllow.
+func (p UnverifiedPolicy) TokenRoles(usr User) ([]Role, error) {
+	if usr.EmailVerified() {
+		return usr.Roles, nil
+	}
+
+	switch p {
+	case UnverifiedDeny:
+		return nil, ErrEmailNotVerified
+	case UnverifiedRestrict:
+		return []Role{RoleUnverified}, nil
+	}
+
+	return usr.Roles, nil
+}
+
+// =============================================================================
+
+// IssueVerificationToken generates a token the user can use to confirm they
+// own their email address.
+func (c *Core) IssueVerificationToken(ctx context.Context, usr User, ttl time.Duration, cooldown time.Duration) (string, error) {
+	if usr.EmailVerified() {
+		return "", ErrEmailAlreadyVerified
+	}
+
+	return c.IssueToken(ctx, usr, TokenEmailVerification, ttl, cooldown)
+}
+
+// VerifyEmail validates the email verification token and marks the email of
+// the user it was issued to as verified.
+func (c *Core) VerifyEmail(ctx context.Context, token string) (User, error) {
+	hash := hashToken(token)
+	now := time.Now()
+
+	tkn, err := c.storer.QueryToken(ctx, hash, TokenEmailVerification)
+	if err != nil {
+		return User{}, fmt.Errorf("querytoken: %w", err)
+	}
+
+	if now.After(tkn.DateExpires) {
+		return User{}, fmt.Errorf("expired[%s]: %w", tkn.DateExpires, ErrInvalidToken)
+	}
+
+	usr, err := c.QueryByID(ctx, tkn.UserID)
+	if err != nil {
+		return User{}, err
+	}
+
+	usr.EmailVerifiedAt = now
+	usr.DateUpdated = now
+
+	if err := c.storer.UpdateWithToken(ctx, usr, hash); err != nil {
+		return User{}, fmt.Errorf("updatewithtoken: %w", err)
+	}
+	usr.Version++
+
+	return usr, nil
+}
@@ -9,14 +9,18 @@ import (
 	"net/url"
 	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/web"
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
 	uniqueViolation = "23505"
 	undefinedTable  = "1146"
+	duplicateEntry  = 1062
+	deadlock        = 1213
 )
 
 // Set of error variables for CRUD operations.
@@ -24,6 +28,7 @@ var (
 	ErrDBNotFound        = sql.ErrNoRows
 	ErrDBDuplicatedEntry = errors.New("duplicated entry")
 	ErrUndefinedTable    = errors.New("undefined table")
+	ErrDBDeadlock        = errors.New("deadlock found, transaction rolled back")
 )
 
 // Config is the required properties to use the database.
@@ -38,8 +43,8 @@ type Config struct {
 	DisableTLS   bool
 }
 
-// Open opens a database specified by a configuration struct 
-// and a driver-specific data source name, usually consisting 
+// Open opens a database specified by a configuration struct
+// and a driver-specific data source name, usually consisting
 // of at least a database name and connection information.
 func Open(config Config) (*sqlx.DB, error) {
 
@@ -50,7 +55,7 @@ func Open(config Config) (*sqlx.DB, error) {
 	q.Set("writeTimeout", "10s")
 	//q.Set("tls", "custom")
 	//TO DO: Add TLS encryption
-	
+
 	u := url.URL{
 		User:     url.UserPassword(config.User, config.Password),
 		Host:     config.Host,
@@ -97,9 +102,14 @@ func NamedExecContext(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 		log.Infoc(ctx, 4, "database.NamedExecContext", "query", q)
 	}
 
-	res, err := sqlx.NamedExecContext(ctx, db, query, data); 
+	// Spans only carry the parameterized statement. The bound values include
+	// password and token hashes which must not leave the service.
+	ctx, span := web.AddSpan(ctx, "business.data.dbsql.mysql.exec", attribute.String("query", query))
+	defer span.End()
+
+	res, err := sqlx.NamedExecContext(ctx, db, query, data)
 	if err != nil {
-		return nil, err
+		return nil, driverError(err)
 	}
 
 	return res, nil
@@ -116,6 +126,9 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 
 	log.Infoc(ctx, 5, "database.NamedQueryStruct", "query", q)
 
+	ctx, span := web.AddSpan(ctx, "business.data.dbsql.mysql.query", attribute.String("query", query))
+	defer span.End()
+
 	var rows *sqlx.Rows
 	var err error
 
@@ -141,7 +154,7 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	}
 
 	if err != nil {
-		return err
+		return driverError(err)
 	}
 	defer rows.Close()
 
@@ -156,6 +169,96 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	return nil
 }
 
+// NamedQuerySlice is a helper function for executing queries that return a
+// collection of data to be unmarshalled into a slice where field replacement is
+// necessary.
+func NamedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, dest *[]T) error {
+	q := queryString(query, data)
+
+	log.Infoc(ctx, 4, "database.NamedQuerySlice", "query", q)
+
+	ctx, span := web.AddSpan(ctx, "business.data.dbsql.mysql.queryslice", attribute.String("query", query))
+	defer span.End()
+
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var slice []T
+	for rows.Next() {
+		v := new(T)
+		if err := rows.StructScan(v); err != nil {
+			return err
+		}
+		slice = append(slice, *v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	*dest = slice
+
+	return nil
+}
+
+// NamedQueryEach is a helper function for executing queries that return a
+// collection of data too large to hold in memory. Each row is unmarshalled
+// into a value of type T and passed to fn as soon as it is read from the
+// cursor. Iteration stops at the first error returned by fn or when the
+// context is canceled.
+func NamedQueryEach[T any](ctx context.Context, log *logger.Logger, db sqlx.ExtContext, query string, data any, fn func(T) error) error {
+	q := queryString(query, data)
+
+	log.Infoc(ctx, 4, "database.NamedQueryEach", "query", q)
+
+	ctx, span := web.AddSpan(ctx, "business.data.dbsql.mysql.queryeach", attribute.String("query", query))
+	defer span.End()
+
+	rows, err := sqlx.NamedQueryContext(ctx, db, query, data)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v T
+		if err := rows.StructScan(&v); err != nil {
+			return err
+		}
+
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// driverError translates the errors of the driver callers need to act on
+// into the errors of this package.
+func driverError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	switch mysqlErr.Number {
+	case duplicateEntry:
+		return ErrDBDuplicatedEntry
+	case deadlock:
+		return ErrDBDeadlock
+	}
+
+	return err
+}
+
 // queryString provides a pretty print version of the query and parameters.
 func queryString(query string, args any) string {
 	query, params, err := sqlx.Named(query, args)
@@ -180,4 +283,4 @@ func queryString(query string, args any) string {
 	query = strings.ReplaceAll(query, "\n", " ")
 
 	return strings.Trim(query, " ")
-}
\ No newline at end of file
+}
@@ -0,0 +1,104 @@
+-- Schema for the MySQL database used by the service. Statements are listed in
+-- the order they need to be applied.
+
+-- Version: 1.01
+-- Description: Create table users
+CREATE TABLE IF NOT EXISTS users (
+	user_id       CHAR(36)     NOT NULL,
+	name          VARCHAR(255) NOT NULL,
+	email         VARCHAR(255) NOT NULL,
+	roles         VARCHAR(255) NOT NULL,
+	password_hash VARBINARY(255) NOT NULL,
+	department    VARCHAR(255) NULL,
+	enabled       BOOLEAN      NOT NULL,
+	date_created  DATETIME     NOT NULL,
+	date_updated  DATETIME     NOT NULL,
+
+	PRIMARY KEY (user_id),
+	UNIQUE KEY users_email_idx (email)
+);
+
+-- Version: 1.02
+-- Description: Create table user_password_history
+CREATE TABLE IF NOT EXISTS user_password_history (
+	history_id    BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	user_id       CHAR(36)        NOT NULL,
+	password_hash VARBINARY(255)  NOT NULL,
+	date_created  DATETIME(6)     NOT NULL,
+
+	PRIMARY KEY (history_id),
+	KEY user_password_history_user_idx (user_id, date_created),
+	CONSTRAINT user_password_history_user_fk FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.03
+-- Description: Track when a user last changed their password
+ALTER TABLE users
+	ADD COLUMN date_password_changed DATETIME NOT NULL DEFAULT '1000-01-01 00:00:00';
+
+-- Version: 1.04
+-- Description: Create table user_tokens
+CREATE TABLE IF NOT EXISTS user_tokens (
+	token_hash   BINARY(32)  NOT NULL,
+	user_id      CHAR(36)    NOT NULL,
+	purpose      VARCHAR(32) NOT NULL,
+	date_created DATETIME    NOT NULL,
+	date_expires DATETIME    NOT NULL,
+
+	PRIMARY KEY (token_hash),
+	KEY user_tokens_user_idx (user_id, purpose),
+	CONSTRAINT user_tokens_user_fk FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.05
+-- Description: Track when a user verified their email
+ALTER TABLE users
+	ADD COLUMN email_verified_at DATETIME NULL;
+
+-- Version: 1.06
+-- Description: Create table sessions
+CREATE TABLE IF NOT EXISTS sessions (
+	session_hash BINARY(32)   NOT NULL,
+	user_id      CHAR(36)     NOT NULL,
+	roles        VARCHAR(255) NOT NULL,
+	csrf_token   VARCHAR(64)  NOT NULL,
+	date_created DATETIME     NOT NULL,
+	date_expires DATETIME     NOT NULL,
+
+	PRIMARY KEY (session_hash),
+	KEY sessions_user_idx (user_id),
+	KEY sessions_expires_idx (date_expires),
+	CONSTRAINT sessions_user_fk FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.07
+-- Description: Create table rate_limits
+CREATE TABLE IF NOT EXISTS rate_limits (
+	bucket_key   VARCHAR(255) NOT NULL,
+	tokens       DOUBLE       NOT NULL,
+	date_updated DATETIME(6)  NOT NULL,
+	date_full    DATETIME(6)  NOT NULL,
+
+	PRIMARY KEY (bucket_key),
+	KEY rate_limits_full_idx (date_full)
+);
+
+-- Version: 1.08
+-- Description: Add a version to users for optimistic concurrency
+ALTER TABLE users
+	ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- Version: 1.09
+-- Description: Create table idempotency_keys
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	idempotency_key BINARY(32) NOT NULL,
+	fingerprint     BINARY(32) NOT NULL,
+	status_code     INT        NOT NULL,
+	header          TEXT       NOT NULL,
+	body            MEDIUMBLOB NULL,
+	date_created    DATETIME   NOT NULL,
+	date_expires    DATETIME   NOT NULL,
+
+	PRIMARY KEY (idempotency_key),
+	KEY idempotency_keys_expires_idx (date_expires)
+);
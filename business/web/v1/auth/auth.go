@@ -9,13 +9,18 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/hpetrov29/restapi/business/core/user"
+	"github.com/hpetrov29/restapi/business/core/user/stores/usersqldb"
+	"github.com/hpetrov29/restapi/business/web/v1/metrics"
 	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/web"
 	"github.com/jmoiron/sqlx"
 	"github.com/open-policy-agent/opa/rego"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ErrForbidden is returned when a auth issue is identified.
@@ -25,6 +30,21 @@ var ErrForbidden = errors.New("insufficient permissions")
 type Claims struct {
 	jwt.RegisteredClaims
 	Roles []user.Role `json:"roles"`
+	Actor *Actor      `json:"act,omitempty"`
+}
+
+// Actor identifies the party acting on behalf of the subject of a token, as
+// described by the RFC 8693 "act" claim. A chain of delegation is expressed
+// by nesting actors, the outermost being the current actor.
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// IsImpersonated reports whether the claims were issued to an actor acting
+// as the subject.
+func (c Claims) IsImpersonated() bool {
+	return c.Actor != nil
 }
 
 type Vault interface {
@@ -34,34 +54,44 @@ type Vault interface {
 
 // Config represents information required to initialize auth.
 type Config struct {
-	Log       *logger.Logger
-	DB        *sqlx.DB
-	Issuer    string
-	Vault Vault	
+	Log    *logger.Logger
+	DB     *sqlx.DB
+	Issuer string
+	Vault  Vault
 }
 
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 type Auth struct {
-	log       *logger.Logger
-	vault 	  Vault
-	method    jwt.SigningMethod
-	parser    *jwt.Parser
-	issuer    string
-	mu        sync.RWMutex
-	cache     map[string]string
+	log     *logger.Logger
+	vault   Vault
+	usrCore *user.Core
+	method  jwt.SigningMethod
+	parser  *jwt.Parser
+	issuer  string
+	mu      sync.RWMutex
+	cache   map[string]string
 }
 
 // New creates an Auth to support authentication/authorization.
 func New(cfg Config) (*Auth, error) {
 
+	// If a database connection is not provided, we won't perform the
+	// user validity check. Only lookups are performed, so no password
+	// hasher or policy is needed.
+	var usrCore *user.Core
+	if cfg.DB != nil {
+		usrCore = user.NewCore(usersqldb.NewStore(cfg.Log, cfg.DB), cfg.Log, nil, user.PasswordPolicy{})
+	}
+
 	a := Auth{
-		log:       cfg.Log,
-		vault:     cfg.Vault,
-		method:    jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
-		parser:    jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
-		issuer:    cfg.Issuer,
-		cache:     make(map[string]string),
+		log:     cfg.Log,
+		vault:   cfg.Vault,
+		usrCore: usrCore,
+		method:  jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
+		parser:  jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
+		issuer:  cfg.Issuer,
+		cache:   make(map[string]string),
 	}
 
 	return &a, nil
@@ -130,9 +160,27 @@ func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, er
 		return Claims{}, fmt.Errorf("authentication failed : %w", err)
 	}
 
+	// Check the database for this user to verify the token hasn't been
+	// revoked since it was issued.
+	if err := a.isUserValid(ctx, claims); err != nil {
+		return Claims{}, fmt.Errorf("user not valid : %w", err)
+	}
+
 	return claims, nil
 }
 
+// ValidateUser checks the user of the claims is still enabled and hasn't
+// changed their password since the claims were issued. Authenticate does it
+// for tokens, claims built any other way, like from a session, must be
+// checked with it.
+func (a *Auth) ValidateUser(ctx context.Context, claims Claims) error {
+	if err := a.isUserValid(ctx, claims); err != nil {
+		return fmt.Errorf("user not valid : %w", err)
+	}
+
+	return nil
+}
+
 // Authorize attempts to authorize the user with the provided input roles, if
 // none of the input roles are within the user's claims, we return an error
 // otherwise the user is authorized.
@@ -153,6 +201,9 @@ func (a *Auth) Authorize(ctx context.Context, claims Claims, userID uuid.UUID, r
 // opaPolicyEvaluation asks opa to evaulate the token against the specified token
 // policy and public key.
 func (a *Auth) opaPolicyEvaluation(ctx context.Context, opaPolicy string, rule string, input any) error {
+	ctx, span := web.AddSpan(ctx, "business.web.v1.auth.opapolicyevaluation", attribute.String("rule", rule))
+	defer span.End()
+
 	query := fmt.Sprintf("x = data.%s.%s", opaPackage, rule)
 
 	q, err := rego.New(
@@ -163,7 +214,9 @@ func (a *Auth) opaPolicyEvaluation(ctx context.Context, opaPolicy string, rule s
 		return err
 	}
 
+	start := time.Now()
 	results, err := q.Eval(ctx, rego.EvalInput(input))
+	metrics.AddOPAEvaluation(rule, time.Since(start))
 	if err != nil {
 		return fmt.Errorf("query: %w", err)
 	}
@@ -180,6 +233,58 @@ func (a *Auth) opaPolicyEvaluation(ctx context.Context, opaPolicy string, rule s
 	return nil
 }
 
+// PolicyCheck verifies the OPA policies compile so it can be used as a
+// readiness check.
+func (a *Auth) PolicyCheck(ctx context.Context) error {
+	policies := map[string]string{
+		RuleAuthenticate: opaAuthentication,
+		RuleAdminOnly:    opaAuthorization,
+	}
+
+	for rule, policy := range policies {
+		query := fmt.Sprintf("x = data.%s.%s", opaPackage, rule)
+
+		_, err := rego.New(
+			rego.Query(query),
+			rego.Module("policy.rego", policy),
+		).PrepareForEval(ctx)
+		if err != nil {
+			return fmt.Errorf("compiling policy for rule %s: %w", rule, err)
+		}
+	}
+
+	return nil
+}
+
+// isUserValid checks the user is still enabled and hasn't changed their
+// password since the token was issued, which revokes every outstanding token.
+func (a *Auth) isUserValid(ctx context.Context, claims Claims) error {
+	if a.usrCore == nil {
+		return nil
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return fmt.Errorf("parse user: %w", err)
+	}
+
+	usr, err := a.usrCore.QueryByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("query user: %w", err)
+	}
+
+	if !usr.Enabled {
+		return errors.New("user disabled")
+	}
+
+	// Token timestamps only carry second precision.
+	if claims.IssuedAt == nil || claims.IssuedAt.Before(usr.DatePasswordChanged.Truncate(time.Second)) {
+		return errors.New("token revoked by password change")
+	}
+
+	return nil
+}
+
 // publicKeyLookup performs a lookup for the public pem for the specified kid.
 func (a *Auth) publicKeyLookup(kid string) (string, error) {
 	pem, err := func() (string, error) {
@@ -206,4 +311,4 @@ func (a *Auth) publicKeyLookup(kid string) (string, error) {
 	a.cache[kid] = pem
 
 	return pem, nil
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 )
@@ -34,4 +35,14 @@ func GetClaims(ctx context.Context) Claims {
 // SetUserID stores the user id from the request in the context.
 func SetUserID(ctx context.Context, userID uuid.UUID) context.Context {
 	return context.WithValue(ctx, userKey, userID)
-}
\ No newline at end of file
+}
+
+// GetUserID returns the user id from the context.
+func GetUserID(ctx context.Context) (uuid.UUID, error) {
+	v, ok := ctx.Value(userKey).(uuid.UUID)
+	if !ok {
+		return uuid.UUID{}, errors.New("user id not found in context")
+	}
+
+	return v, nil
+}
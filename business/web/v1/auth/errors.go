@@ -28,4 +28,30 @@ func NewAuthError(format string, args ...any) error {
 func IsAuthError(err error) bool {
 	var ae *AuthError
 	return errors.As(err, &ae)
-}
\ No newline at end of file
+}
+
+// ForbiddenError is used to pass an error during the request through the
+// application when an authenticated caller isn't allowed to do what they
+// are requesting to do.
+type ForbiddenError struct {
+	msg string
+}
+
+// Error implements the error interface. This is what will be shown in the
+// services' logs.
+func (fe *ForbiddenError) Error() string {
+	return fe.msg
+}
+
+// NewForbiddenError creates a ForbiddenError for the provided message.
+func NewForbiddenError(format string, args ...any) error {
+	return &ForbiddenError{
+		msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// IsForbiddenError checks if an error of type ForbiddenError exists.
+func IsForbiddenError(err error) bool {
+	var fe *ForbiddenError
+	return errors.As(err, &fe)
+}
@@ -0,0 +1,68 @@
+// Package debug provides handler support for the debugging endpoints.
+package debug
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/hpetrov29/restapi/business/web/v1/metrics"
+)
+
+// Mux registers all the debug routes from the standard library into a new
+// mux bypassing the use of the DefaultServerMux. Using the DefaultServerMux
+// would be a security risk since a dependency could inject a handler into
+// our service without us knowing it.
+func Mux(build string) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/debug/build", buildHandler(build))
+	mux.Handle("/metrics", metrics.Handler())
+
+	return mux
+}
+
+// =============================================================================
+
+// buildInfo represents the version information of the running binary.
+type buildInfo struct {
+	Build     string            `json:"build"`
+	GoVersion string            `json:"goVersion"`
+	Path      string            `json:"path,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+// buildHandler reports the service build along with the module and VCS
+// information embedded in the binary by the Go toolchain.
+func buildHandler(build string) http.Handler {
+	info := buildInfo{
+		Build:     build,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Path = bi.Main.Path
+		info.Version = bi.Main.Version
+		info.Settings = make(map[string]string, len(bi.Settings))
+		for _, s := range bi.Settings {
+			info.Settings[s.Key] = s.Value
+		}
+	}
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+
+	return http.HandlerFunc(h)
+}
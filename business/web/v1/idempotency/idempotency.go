@@ -0,0 +1,47 @@
+// Package idempotency provides support for storing the responses of unsafe
+// requests so retries carrying the same Idempotency-Key can be replayed
+// instead of being processed again.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Set of error variables for idempotency records.
+var (
+	ErrNotFound = errors.New("idempotency record not found")
+	ErrExists   = errors.New("idempotency record already exists")
+)
+
+// Record represents a request made with an idempotency key. A record with a
+// zero Status is locked by the request still being processed.
+type Record struct {
+	Key         []byte
+	Fingerprint []byte
+	Status      int
+	Header      http.Header
+	Body        []byte
+	DateCreated time.Time
+	DateExpires time.Time
+}
+
+// InProgress reports whether the request is still being processed.
+func (r Record) InProgress() bool {
+	return r.Status == 0
+}
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve records.
+type Storer interface {
+	// Lock stores the record unless an unexpired record with the same key
+	// exists, in which case ErrExists is returned.
+	Lock(ctx context.Context, rec Record) error
+	QueryByKey(ctx context.Context, key []byte) (Record, error)
+
+	// Complete stores the response and the expiry of the record.
+	Complete(ctx context.Context, rec Record) error
+	Delete(ctx context.Context, key []byte) error
+}
@@ -0,0 +1,153 @@
+// Package idempotencysqldb contains idempotency record related CRUD
+// functionality.
+package idempotencysqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/hpetrov29/restapi/business/data/dbsql/mysql"
+	"github.com/hpetrov29/restapi/business/web/v1/idempotency"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for idempotency record database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Lock inserts the record unless an unexpired record with the same key
+// exists. The primary key guarantees only one of several concurrent
+// requests acquires the lock.
+func (s *Store) Lock(ctx context.Context, rec idempotency.Record) error {
+	data := struct {
+		Key []byte    `db:"idempotency_key"`
+		Now time.Time `db:"now"`
+	}{
+		Key: rec.Key,
+		Now: time.Now().UTC(),
+	}
+
+	const qExpired = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		idempotency_key = :idempotency_key AND
+		date_expires < :now`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, qExpired, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	dbRec, err := toDBRecord(rec)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO idempotency_keys
+		(idempotency_key, fingerprint, status_code, header, body, date_created, date_expires)
+	VALUES
+		(:idempotency_key, :fingerprint, :status_code, :header, :body, :date_created, :date_expires)`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, dbRec); err != nil {
+		if errors.Is(err, db.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", idempotency.ErrExists)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByKey gets the specified record from the database.
+func (s *Store) QueryByKey(ctx context.Context, key []byte) (idempotency.Record, error) {
+	data := struct {
+		Key []byte `db:"idempotency_key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	SELECT
+		idempotency_key, fingerprint, status_code, header, body, date_created, date_expires
+	FROM
+		idempotency_keys
+	WHERE
+		idempotency_key = :idempotency_key`
+
+	var dbRec dbRecord
+	if err := db.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbRec); err != nil {
+		if errors.Is(err, db.ErrDBNotFound) {
+			return idempotency.Record{}, fmt.Errorf("namedquerystruct: %w", idempotency.ErrNotFound)
+		}
+		return idempotency.Record{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	rec, err := toCoreRecord(dbRec)
+	if err != nil {
+		return idempotency.Record{}, err
+	}
+
+	return rec, nil
+}
+
+// Complete stores the response of the request which holds the lock and
+// extends the expiry of the record from the lease to the time the response
+// is kept for.
+func (s *Store) Complete(ctx context.Context, rec idempotency.Record) error {
+	dbRec, err := toDBRecord(rec)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	UPDATE
+		idempotency_keys
+	SET
+		status_code = :status_code,
+		header = :header,
+		body = :body,
+		date_expires = :date_expires
+	WHERE
+		idempotency_key = :idempotency_key`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, dbRec); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the record, releasing the lock.
+func (s *Store) Delete(ctx context.Context, key []byte) error {
+	data := struct {
+		Key []byte `db:"idempotency_key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	DELETE FROM
+		idempotency_keys
+	WHERE
+		idempotency_key = :idempotency_key`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
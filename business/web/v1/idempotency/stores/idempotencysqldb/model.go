@@ -0,0 +1,62 @@
+package idempotencysqldb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hpetrov29/restapi/business/web/v1/idempotency"
+)
+
+// dbRecord represent the structure we need for moving data
+// between the app and the database.
+type dbRecord struct {
+	Key         []byte    `db:"idempotency_key"`
+	Fingerprint []byte    `db:"fingerprint"`
+	Status      int       `db:"status_code"`
+	Header      []byte    `db:"header"`
+	Body        []byte    `db:"body"`
+	DateCreated time.Time `db:"date_created"`
+	DateExpires time.Time `db:"date_expires"`
+}
+
+func toDBRecord(rec idempotency.Record) (dbRecord, error) {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return dbRecord{}, fmt.Errorf("marshal header: %w", err)
+	}
+
+	dbRec := dbRecord{
+		Key:         rec.Key,
+		Fingerprint: rec.Fingerprint,
+		Status:      rec.Status,
+		Header:      header,
+		Body:        rec.Body,
+		DateCreated: rec.DateCreated.UTC(),
+		DateExpires: rec.DateExpires.UTC(),
+	}
+
+	return dbRec, nil
+}
+
+func toCoreRecord(dbRec dbRecord) (idempotency.Record, error) {
+	var header http.Header
+	if len(dbRec.Header) > 0 {
+		if err := json.Unmarshal(dbRec.Header, &header); err != nil {
+			return idempotency.Record{}, fmt.Errorf("unmarshal header: %w", err)
+		}
+	}
+
+	rec := idempotency.Record{
+		Key:         dbRec.Key,
+		Fingerprint: dbRec.Fingerprint,
+		Status:      dbRec.Status,
+		Header:      header,
+		Body:        dbRec.Body,
+		DateCreated: dbRec.DateCreated.In(time.Local),
+		DateExpires: dbRec.DateExpires.In(time.Local),
+	}
+
+	return rec, nil
+}
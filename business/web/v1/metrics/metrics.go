@@ -0,0 +1,120 @@
+// Package metrics constructs the metrics the application will track and
+// exposes them through a dedicated registry.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the name of every metric of the service.
+const namespace = "api"
+
+// registry holds every metric tracked by the service. A dedicated registry
+// is used instead of the global default so only what is registered here is
+// exposed.
+var registry = prometheus.NewRegistry()
+
+var (
+	panics = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_total",
+		Help:      "Number of panics recovered while handling requests.",
+	})
+
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Number of requests handled by route and status class.",
+	}, []string{"method", "route", "status"})
+
+	duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the requests handled by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	opaDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "opa_evaluation_duration_seconds",
+		Help:      "Latency of the OPA policy evaluations by rule.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25},
+	}, []string{"rule"})
+
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Number of requests rejected by authentication or authorization.",
+	}, []string{"stage"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		panics,
+		requests,
+		duration,
+		opaDuration,
+		authFailures,
+	)
+}
+
+// Registry returns the registry the metrics are registered with.
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// Handler returns a handler serving the metrics in the Prometheus text
+// exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB adds the connection pool stats of the database to the metrics.
+func RegisterDB(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// AddPanics increments the panics metric by 1.
+func AddPanics() {
+	panics.Inc()
+}
+
+// AddRequest records a completed request for the route. Status codes are
+// grouped by class to keep the number of series small.
+func AddRequest(method string, route string, statusCode int, since time.Duration) {
+	requests.WithLabelValues(method, route, statusClass(statusCode)).Inc()
+	duration.WithLabelValues(method, route).Observe(since.Seconds())
+}
+
+// AddOPAEvaluation records the latency of an OPA policy evaluation.
+func AddOPAEvaluation(rule string, since time.Duration) {
+	opaDuration.WithLabelValues(rule).Observe(since.Seconds())
+}
+
+// Set of stages an auth failure can happen at.
+const (
+	StageAuthenticate = "authenticate"
+	StageAuthorize    = "authorize"
+)
+
+// AddAuthFailure increments the auth failures metric for the stage by 1.
+func AddAuthFailure(stage string) {
+	authFailures.WithLabelValues(stage).Inc()
+}
+
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+
+	return strconv.Itoa(statusCode/100) + "xx"
+}
@@ -5,26 +5,63 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/hpetrov29/restapi/business/web/v1/auth"
+	"github.com/hpetrov29/restapi/business/web/v1/metrics"
 	"github.com/hpetrov29/restapi/business/web/v1/response"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
+	"github.com/hpetrov29/restapi/internal/logger"
 	"github.com/hpetrov29/restapi/internal/web"
 )
 
 // Set of error variables for handling user group errors.
 var (
-	ErrInvalidID = errors.New("ID is not in its proper form")
+	ErrInvalidID     = errors.New("ID is not in its proper form")
+	ErrImpersonating = errors.New("action not allowed while impersonating")
 )
 
-// Authenticate validates a JWT from the `Authorization` header.
-func Authenticate(a *auth.Auth) web.Middleware {
+// Authenticate validates a JWT from the `Authorization` header. When the
+// header is absent and a session manager is provided, the session cookie is
+// validated instead and the session is stored in the context next to the
+// same claims a token would carry. Every request made with an impersonation
+// token is logged with both identities.
+func Authenticate(log *logger.Logger, a *auth.Auth, sm *session.Manager) web.Middleware {
 	m := func(handler web.Handler) web.Handler {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-			claims, err := a.Authenticate(ctx, r.Header.Get("authorization"))
+			bearer := r.Header.Get("authorization")
+
+			if bearer == "" && sm != nil {
+				sess, err := sm.Authenticate(ctx, w, r)
+				if err != nil {
+					metrics.AddAuthFailure(metrics.StageAuthenticate)
+					return auth.NewAuthError("authenticate: session failed: %s", err)
+				}
+
+				// Sessions are checked against the user like tokens are, so
+				// disabling a user or changing their password ends them.
+				claims := sessionClaims(sess)
+				if err := a.ValidateUser(ctx, claims); err != nil {
+					metrics.AddAuthFailure(metrics.StageAuthenticate)
+					return auth.NewAuthError("authenticate: session failed: %s", err)
+				}
+
+				ctx = session.Set(ctx, sess)
+				ctx = auth.SetClaims(ctx, claims)
+
+				return handler(ctx, w, r)
+			}
+
+			claims, err := a.Authenticate(ctx, bearer)
 			if err != nil {
+				metrics.AddAuthFailure(metrics.StageAuthenticate)
 				return auth.NewAuthError("authenticate: failed: %s", err)
 			}
 
+			if claims.IsImpersonated() {
+				log.Info(ctx, "impersonated request", "method", r.Method, "path", r.URL.Path, "subject", claims.Subject, "actor", claims.Actor.Subject)
+			}
+
 			ctx = auth.SetClaims(ctx, claims)
 
 			return handler(ctx, w, r)
@@ -36,6 +73,37 @@ func Authenticate(a *auth.Auth) web.Middleware {
 	return m
 }
 
+// sessionClaims builds the claims equivalent to the session.
+func sessionClaims(sess session.Session) auth.Claims {
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sess.UserID.String(),
+			IssuedAt:  jwt.NewNumericDate(sess.DateCreated),
+			ExpiresAt: jwt.NewNumericDate(sess.DateExpires),
+		},
+		Roles: sess.Roles,
+	}
+}
+
+// DenyImpersonation rejects the request when it is made with an impersonation
+// token. It guards sensitive operations, like changing credentials, which
+// only the user themselves may perform. It must run after Authenticate.
+func DenyImpersonation() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if auth.GetClaims(ctx).IsImpersonated() {
+				return response.NewError(ErrImpersonating, http.StatusForbidden)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
 // Authorize validates that an authenticated user has at least one role from a
 // specified list. This method constructs the actual function that is used.
 func Authorize(a *auth.Auth, rule string) web.Middleware {
@@ -43,6 +111,7 @@ func Authorize(a *auth.Auth, rule string) web.Middleware {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			claims := auth.GetClaims(ctx)
 			if claims.Subject == "" {
+				metrics.AddAuthFailure(metrics.StageAuthorize)
 				return auth.NewAuthError("authorize: you are not authorized for that action, no claims")
 			}
 
@@ -59,7 +128,8 @@ func Authorize(a *auth.Auth, rule string) web.Middleware {
 			}
 
 			if err := a.Authorize(ctx, claims, userID, rule); err != nil {
-				return auth.NewAuthError("authorize: you are not authorized for that action, claims[%v] rule[%v]: %s", claims.Roles, rule, err)
+				metrics.AddAuthFailure(metrics.StageAuthorize)
+				return auth.NewForbiddenError("authorize: you are not authorized for that action, claims[%v] rule[%v]: %s", claims.Roles, rule, err)
 			}
 
 			return handler(ctx, w, r)
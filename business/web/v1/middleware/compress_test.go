@@ -0,0 +1,34 @@
+package middleware
+
+import "testing"
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"single", "gzip", "gzip"},
+		{"case insensitive", "GZip", "gzip"},
+		{"preference on equal weight", "gzip, deflate, zstd", "zstd"},
+		{"weights", "zstd;q=0.5, gzip;q=0.8", "gzip"},
+		{"spaces around weight", "deflate ; q=0.9, gzip; q=0.1", "deflate"},
+		{"refused", "gzip;q=0", ""},
+		{"only identity", "identity", ""},
+		{"wildcard", "*", "zstd"},
+		{"wildcard with refusals", "*, zstd;q=0, gzip;q=0", "deflate"},
+		{"explicit beats wildcard", "*;q=0.1, gzip", "gzip"},
+		{"wildcard refused", "*;q=0", ""},
+		{"malformed weight ignored", "zstd;q=high, gzip", "gzip"},
+		{"unknown encoding", "br", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
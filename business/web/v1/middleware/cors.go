@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// ErrCORSCredentials is returned when credentials are allowed for any origin.
+var ErrCORSCredentials = errors.New("credentials can only be allowed for an explicit list of origins")
+
+// CORSConfig contains the cross origin resource sharing settings.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API. An entry can
+	// contain a single * wildcard, like https://*.example.com, and * alone
+	// allows every origin. With AllowCredentials a wildcard can only stand
+	// for subdomains. An empty list allows no origin.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS sets the response headers needed for cross origin requests and
+// answers preflight requests. Requests from an origin which isn't allowed
+// get no CORS headers, so the browser blocks them. It must be enabled with
+// web.App.EnableCORS so preflight requests are routed.
+//
+// Allowing credentials for any origin would let every site make requests
+// with the cookies of its visitors and read the responses, so an error is
+// returned when credentials are combined with a wildcard which isn't limited
+// to subdomains.
+func CORS(cfg CORSConfig) (web.Middleware, error) {
+	allowAll := false
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+
+		if cfg.AllowCredentials && !subdomainPattern(origin) {
+			return nil, fmt.Errorf("origin %q: %w", origin, ErrCORSCredentials)
+		}
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// The response differs by origin, so caches must not share it
+			// between origins.
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" || !(allowAll || matchOrigin(cfg.AllowedOrigins, origin)) {
+				return handler(ctx, w, r)
+			}
+
+			switch {
+			case allowAll:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			default:
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposed != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposed)
+				}
+				return handler(ctx, w, r)
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+
+			// Without a configured list the requested headers are allowed.
+			switch {
+			case headers != "":
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			case r.Header.Get("Access-Control-Request-Headers") != "":
+				w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+			}
+
+			if cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+
+		return h
+	}
+
+	return m, nil
+}
+
+// subdomainPattern reports whether the allowed origin is an exact origin or
+// only has a wildcard in place of subdomains, like https://*.example.com.
+func subdomainPattern(pattern string) bool {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return true
+	}
+
+	return strings.HasSuffix(prefix, "://") && strings.HasPrefix(suffix, ".") && strings.Count(suffix, ".") >= 2
+}
+
+// matchOrigin reports whether the origin matches one of the allowed origins.
+func matchOrigin(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if pattern == origin {
+				return true
+			}
+			continue
+		}
+
+		if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
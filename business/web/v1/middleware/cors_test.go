@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"exact", []string{"https://example.com"}, "https://example.com", true},
+		{"case insensitive", []string{"https://Example.com"}, "HTTPS://example.COM", true},
+		{"other origin", []string{"https://example.com"}, "https://evil.com", false},
+		{"other scheme", []string{"https://example.com"}, "http://example.com", false},
+		{"other port", []string{"https://example.com"}, "https://example.com:8443", false},
+		{"suffix of allowed", []string{"https://example.com"}, "https://example.com.evil.com", false},
+		{"subdomain", []string{"https://*.example.com"}, "https://api.example.com", true},
+		{"nested subdomain", []string{"https://*.example.com"}, "https://a.b.example.com", true},
+		{"apex against subdomain wildcard", []string{"https://*.example.com"}, "https://example.com", false},
+		{"lookalike domain", []string{"https://*.example.com"}, "https://evilexample.com", false},
+		{"wildcard scheme mismatch", []string{"https://*.example.com"}, "http://api.example.com", false},
+		{"overlapping prefix and suffix", []string{"https://a*a"}, "https://a", false},
+		{"second entry", []string{"https://one.com", "https://two.com"}, "https://two.com", true},
+		{"no entries", nil, "https://example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchOrigin(tt.allowed, tt.origin); got != tt.want {
+				t.Errorf("matchOrigin(%q, %q) = %v, want %v", tt.allowed, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		wantErr bool
+	}{
+		{"explicit origins", []string{"https://example.com", "http://localhost:3000"}, false},
+		{"subdomain wildcard", []string{"https://*.example.com"}, false},
+		{"any origin", []string{"*"}, true},
+		{"any origin among others", []string{"https://example.com", "*"}, true},
+		{"any host", []string{"https://*"}, true},
+		{"any subdomain of a tld", []string{"https://*.com"}, true},
+		{"wildcard in the domain", []string{"https://example*.com"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CORS(CORSConfig{AllowedOrigins: tt.origins, AllowCredentials: true})
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("CORS(%q) error = %v, want error %v", tt.origins, err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrCORSCredentials) {
+				t.Errorf("CORS(%q) error = %v, want %v", tt.origins, err, ErrCORSCredentials)
+			}
+		})
+	}
+
+	if _, err := CORS(CORSConfig{AllowedOrigins: []string{"*"}}); err != nil {
+		t.Errorf("CORS without credentials must allow any origin, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/hpetrov29/restapi/business/web/v1/response"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// CSRFHeader is the header that must carry the session's CSRF token on
+// unsafe requests authenticated with a session cookie.
+const CSRFHeader = "X-CSRF-Token"
+
+// ErrCSRF is returned when an unsafe request lacks a valid CSRF token.
+var ErrCSRF = errors.New("missing or invalid csrf token")
+
+// CSRF implements the synchronizer token pattern for requests authenticated
+// with a session cookie. Unsafe methods must echo the token issued with the
+// session in the X-CSRF-Token header. Requests authenticated with a bearer
+// token aren't exposed to CSRF and pass through. It must run after
+// Authenticate.
+func CSRF() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			sess, ok := session.Get(ctx)
+			if !ok || isSafeMethod(r.Method) {
+				return handler(ctx, w, r)
+			}
+
+			token := r.Header.Get(CSRFHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(sess.CSRFToken)) != 1 {
+				return response.NewError(ErrCSRF, http.StatusForbidden)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
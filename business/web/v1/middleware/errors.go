@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/hpetrov29/restapi/business/web/v1/auth"
+	"github.com/hpetrov29/restapi/business/web/v1/response"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/validate"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// Errors handles errors coming out of the call chain. It detects normal
+// application errors which are used to respond to the client in a uniform
+// way. Unexpected errors (status >= 500) are logged as errors and hidden
+// behind a generic message so internal details don't leak to the client.
+func Errors(log *logger.Logger) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := handler(ctx, w, r)
+			if err == nil {
+				return nil
+			}
+
+			var er response.ErrorDocument
+			var status int
+
+			switch {
+			case errors.Is(err, web.ErrBodyTooLarge):
+				er = response.ErrorDocument{
+					Error: err.Error(),
+				}
+				status = http.StatusRequestEntityTooLarge
+
+			case errors.Is(err, web.ErrUnsupportedMediaType), errors.Is(err, web.ErrUnsupportedEncoding):
+				er = response.ErrorDocument{
+					Error: err.Error(),
+				}
+				status = http.StatusUnsupportedMediaType
+
+			case errors.Is(err, web.ErrNotAcceptable):
+				er = response.ErrorDocument{
+					Error: err.Error(),
+				}
+				status = http.StatusNotAcceptable
+
+			case validate.IsFieldErrors(err):
+				fieldErrors := validate.GetFieldErrors(err)
+				er = response.ErrorDocument{
+					Error:  "data validation error",
+					Fields: fieldErrors.Fields(),
+				}
+				status = http.StatusBadRequest
+
+			case auth.IsForbiddenError(err):
+				er = response.ErrorDocument{
+					Error: http.StatusText(http.StatusForbidden),
+				}
+				status = http.StatusForbidden
+
+			case auth.IsAuthError(err):
+				er = response.ErrorDocument{
+					Error: http.StatusText(http.StatusUnauthorized),
+				}
+				status = http.StatusUnauthorized
+
+			case response.IsError(err):
+				reqErr := response.GetError(err)
+				er = response.ErrorDocument{
+					Error: reqErr.Error(),
+				}
+				status = reqErr.Status
+
+				if status >= http.StatusInternalServerError {
+					er.Error = http.StatusText(status)
+				}
+
+			default:
+				er = response.ErrorDocument{
+					Error: http.StatusText(http.StatusInternalServerError),
+				}
+				status = http.StatusInternalServerError
+			}
+
+			// Client errors are part of normal operation, only failures of
+			// the service are worth an alert.
+			if status >= http.StatusInternalServerError {
+				log.Error(ctx, "request failed", "method", r.Method, "path", r.URL.Path, "status", status, "msg", err)
+			} else {
+				log.Info(ctx, "request failed", "method", r.Method, "path", r.URL.Path, "status", status, "msg", err)
+			}
+
+			if err := web.Respond(ctx, w, er, status); err != nil {
+				return err
+			}
+
+			// If we receive the shutdown err we need to return it
+			// back to the base handler to shut down the service.
+			if web.IsShutdown(err) {
+				return err
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hpetrov29/restapi/business/web/v1/idempotency"
+	"github.com/hpetrov29/restapi/business/web/v1/response"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key chosen by
+// the client for a request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Set of limits applied to idempotent requests.
+const (
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBody       = 1 << 20
+)
+
+// idempotencyLease is how long the lock of a request being processed is
+// held. It outlives any request, but a key locked by a request which never
+// completed, because the process died, becomes usable again soon after.
+const idempotencyLease = time.Minute
+
+// Set of error variables for idempotent requests.
+var (
+	ErrIdempotencyKey        = errors.New("the Idempotency-Key header must be at most 255 characters")
+	ErrIdempotencyMismatch   = errors.New("the Idempotency-Key was already used with a different request")
+	ErrIdempotencyInProgress = errors.New("a request with the same Idempotency-Key is being processed")
+	ErrIdempotencyBody       = errors.New("request body too large for an idempotent request")
+)
+
+// replayedHeaders lists the response headers stored with the response. The
+// others are set again by the middlewares when the response is replayed.
+var replayedHeaders = []string{"Content-Type", "Location", web.ETagHeader}
+
+// Idempotency makes an unsafe request carrying an Idempotency-Key header
+// safe to retry. The first response for a key is stored for ttl and
+// replayed for retries of the same request. Reusing the key with a different
+// request is rejected with a 422, and a retry which arrives while the first
+// request is still processed with a 409. Requests without the header are
+// processed as usual. Failed requests aren't stored so they can be retried.
+// The key is only locked for a short lease while the first request is
+// processed, the ttl starts once its response is stored.
+func Idempotency(log *logger.Logger, st idempotency.Storer, ttl time.Duration, keyFn ClientKey) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		if st == nil {
+			return handler
+		}
+
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || isSafeMethod(r.Method) {
+				return handler(ctx, w, r)
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				return response.NewError(ErrIdempotencyKey, http.StatusBadRequest)
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+			if err != nil {
+				return response.NewError(fmt.Errorf("reading body: %w", err), http.StatusBadRequest)
+			}
+			if len(body) > maxIdempotentBody {
+				return response.NewError(ErrIdempotencyBody, http.StatusRequestEntityTooLarge)
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Keys are scoped to the client and route so clients can't
+			// collide with each other.
+			scoped := sha256.Sum256([]byte(r.Method + "\n" + routePattern(r) + "\n" + keyFn(ctx, r) + "\n" + key))
+			fingerprint := sha256.Sum256(append([]byte(r.Method+"\n"+r.URL.RequestURI()+"\n"), body...))
+
+			now := time.Now()
+			rec := idempotency.Record{
+				Key:         scoped[:],
+				Fingerprint: fingerprint[:],
+				DateCreated: now,
+				DateExpires: now.Add(idempotencyLease),
+			}
+
+			if err := st.Lock(ctx, rec); err != nil {
+				if !errors.Is(err, idempotency.ErrExists) {
+					return fmt.Errorf("lock: %w", err)
+				}
+				return replay(ctx, w, st, rec)
+			}
+
+			rw := &recordingWriter{ResponseWriter: w}
+
+			if err := handler(ctx, rw, r); err != nil || rw.status >= http.StatusInternalServerError {
+				if err := st.Delete(ctx, rec.Key); err != nil {
+					log.Error(ctx, "idempotency: release lock failed", "msg", err)
+				}
+				return err
+			}
+
+			rec.Status = rw.status
+			rec.DateExpires = time.Now().Add(ttl)
+			rec.Body = rw.body.Bytes()
+			rec.Header = make(http.Header)
+			for _, name := range replayedHeaders {
+				if v := rw.Header().Values(name); len(v) > 0 {
+					rec.Header[name] = v
+				}
+			}
+
+			// The response was already sent, a failure only means retries
+			// aren't replayed.
+			if err := st.Complete(ctx, rec); err != nil {
+				log.Error(ctx, "idempotency: complete failed", "msg", err)
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// replay writes the stored response for a repeated request.
+func replay(ctx context.Context, w http.ResponseWriter, st idempotency.Storer, rec idempotency.Record) error {
+	stored, err := st.QueryByKey(ctx, rec.Key)
+	if err != nil {
+		if errors.Is(err, idempotency.ErrNotFound) {
+			return response.NewError(ErrIdempotencyInProgress, http.StatusConflict)
+		}
+		return fmt.Errorf("querybykey: %w", err)
+	}
+
+	if !bytes.Equal(stored.Fingerprint, rec.Fingerprint) {
+		return response.NewError(ErrIdempotencyMismatch, http.StatusUnprocessableEntity)
+	}
+
+	if stored.InProgress() {
+		w.Header().Set("Retry-After", "1")
+		return response.NewError(ErrIdempotencyInProgress, http.StatusConflict)
+	}
+
+	for name, values := range stored.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+
+	web.SetStatusCode(ctx, stored.Status)
+	w.WriteHeader(stored.Status)
+
+	if _, err := w.Write(stored.Body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// recordingWriter keeps a copy of the response written to the client.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code.
+func (rw *recordingWriter) WriteHeader(statusCode int) {
+	if rw.status == 0 {
+		rw.status = statusCode
+	}
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body.
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer for use with http.ResponseController.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
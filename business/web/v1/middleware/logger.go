@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// LoggerConfig contains the settings for the access log.
+type LoggerConfig struct {
+	// Exclude lists request paths which aren't logged, like health checks.
+	Exclude []string
+
+	// SlowThreshold logs a warning for requests that take longer than the
+	// threshold. Zero disables the warning.
+	SlowThreshold time.Duration
+}
+
+// Logger writes information about the request to the logs when it starts and
+// when it completes.
+func Logger(log *logger.Logger, cfg LoggerConfig) web.Middleware {
+	exclude := make(map[string]struct{}, len(cfg.Exclude))
+	for _, path := range cfg.Exclude {
+		exclude[path] = struct{}{}
+	}
+
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if _, skip := exclude[r.URL.Path]; skip {
+				return handler(ctx, w, r)
+			}
+
+			v := web.GetValues(ctx)
+
+			path := r.URL.Path
+			if r.URL.RawQuery != "" {
+				path = fmt.Sprintf("%s?%s", path, r.URL.RawQuery)
+			}
+
+			log.Info(ctx, "request started", "method", r.Method, "path", path, "remoteaddr", r.RemoteAddr)
+
+			cw := &countingWriter{ResponseWriter: w}
+
+			err := handler(ctx, cw, r)
+
+			args := []any{
+				"method", r.Method,
+				"path", path,
+				"route", routePattern(r),
+				"remoteaddr", r.RemoteAddr,
+				"statuscode", cw.statusCode(),
+				"bytes", cw.bytes,
+				"since", time.Since(v.Now),
+			}
+
+			log.Info(ctx, "request completed", args...)
+
+			if cfg.SlowThreshold > 0 && time.Since(v.Now) > cfg.SlowThreshold {
+				log.Warn(ctx, "slow request", append(args, "threshold", cfg.SlowThreshold)...)
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// routePattern returns the pattern of the route that matched the request.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	return rctx.RoutePattern()
+}
+
+// =============================================================================
+
+// countingWriter records the status code of the response and counts the
+// number of bytes written in its body. Unlike the status code Respond keeps
+// in the context, it also sees responses handlers write themselves.
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader records the first final status code written.
+func (cw *countingWriter) WriteHeader(statusCode int) {
+	if cw.status == 0 && statusCode >= http.StatusOK {
+		cw.status = statusCode
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write counts the bytes written to the underlying writer.
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	n, err := cw.ResponseWriter.Write(b)
+	cw.bytes += n
+	return n, err
+}
+
+// statusCode returns the status code of the response. A response nothing
+// was written to is sent by the server with a 200.
+func (cw *countingWriter) statusCode() int {
+	if cw.status == 0 {
+		return http.StatusOK
+	}
+	return cw.status
+}
+
+// Flush implements the http.Flusher interface when the underlying writer
+// supports it.
+func (cw *countingWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface when the underlying writer
+// supports it.
+func (cw *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying writer for use with http.ResponseController.
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
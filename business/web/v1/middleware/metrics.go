@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hpetrov29/restapi/business/web/v1/metrics"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// Metrics records the rate, status and latency of the requests by route. The
+// status is taken from the response writer so responses handlers write
+// themselves are counted too. It must run before Errors so the status code
+// of error responses is known.
+func Metrics() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			cw := &countingWriter{ResponseWriter: w}
+
+			err := handler(ctx, cw, r)
+
+			v := web.GetValues(ctx)
+
+			// Unmatched routes would otherwise create a series per path.
+			route := routePattern(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			metrics.AddRequest(r.Method, route, cw.statusCode(), time.Since(v.Now))
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
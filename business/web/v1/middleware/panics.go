@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/hpetrov29/restapi/business/web/v1/metrics"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// Panics recovers from panics and converts the panic to an error so it is
+// reported in Metrics and handled in Errors. A panic with a shutdown error
+// keeps it in the chain so the framework can signal the shutdown.
+func Panics() web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+
+		// Use the named return value so the deferred function can modify
+		// what is returned once the panic is recovered.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+
+				// The http server uses this panic to abort a response on
+				// purpose, so let it through.
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				trace := debug.Stack()
+
+				if recErr, ok := rec.(error); ok {
+					err = fmt.Errorf("PANIC [%w] TRACE[%s]", recErr, string(trace))
+				} else {
+					err = fmt.Errorf("PANIC [%v] TRACE[%s]", rec, string(trace))
+				}
+
+				metrics.AddPanics()
+			}()
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hpetrov29/restapi/business/web/v1/auth"
+	"github.com/hpetrov29/restapi/business/web/v1/ratelimit"
+	"github.com/hpetrov29/restapi/business/web/v1/response"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// APIKeyHeader is the header carrying the API key of a client.
+const APIKeyHeader = "X-API-Key"
+
+// ErrRateLimited is returned when a client exceeds the limit of a route.
+var ErrRateLimited = errors.New("rate limit exceeded, try again later")
+
+// ClientKey identifies the client making a request.
+type ClientKey func(ctx context.Context, r *http.Request) string
+
+// KeyByIP counts requests against the remote address of the client.
+func KeyByIP(ctx context.Context, r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// KeyBySubject counts requests against the authenticated subject, falling
+// back to the remote address. It must run after Authenticate.
+func KeyBySubject(ctx context.Context, r *http.Request) string {
+	if subject := auth.GetClaims(ctx).Subject; subject != "" {
+		return "sub:" + subject
+	}
+
+	return KeyByIP(ctx, r)
+}
+
+// KeyByAPIKey counts requests against the API key of the client, falling
+// back to the remote address. Only a hash of the key is used so it doesn't
+// end up in storage.
+func KeyByAPIKey(ctx context.Context, r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:])
+	}
+
+	return KeyByIP(ctx, r)
+}
+
+// RateLimit limits the rate of requests a client can make to the route
+// using a token bucket. Every response carries the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, and rejected requests a
+// Retry-After header. A nil store disables the limit. When the store fails
+// the request is let through so an outage doesn't take the API down with it.
+func RateLimit(log *logger.Logger, st ratelimit.Storer, limit ratelimit.Limit, keyFn ClientKey) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		if st == nil {
+			return handler
+		}
+
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := fmt.Sprintf("%s:%s:%s", r.Method, routePattern(r), keyFn(ctx, r))
+
+			res, err := st.Take(ctx, key, limit)
+			if err != nil {
+				log.Error(ctx, "ratelimit: take failed", "key", key, "msg", err)
+				return handler(ctx, w, r)
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", seconds(res.Reset))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", seconds(res.RetryAfter))
+				return response.NewError(ErrRateLimited, http.StatusTooManyRequests)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// seconds formats the duration as a whole number of seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are evicted from memory.
+const sweepInterval = time.Minute
+
+// Memory is a Storer keeping the buckets in process. Limits are not shared
+// between instances, so it's only suited to a single instance deployment.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket   Bucket
+	dateFull time.Time
+}
+
+// NewMemory constructs an empty in memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:   make(map[string]memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take takes a token from the bucket identified by the key.
+func (m *Memory) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, res := limit.Take(m.buckets[key].bucket, now)
+
+	m.buckets[key] = memoryBucket{
+		bucket:   b,
+		dateFull: now.Add(res.Reset),
+	}
+
+	return res, nil
+}
+
+// sweep evicts the buckets which have refilled since they were last used,
+// as they are equivalent to a missing bucket.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, mb := range m.buckets {
+		if now.After(mb.dateFull) {
+			delete(m.buckets, key)
+		}
+	}
+
+	m.lastSweep = now
+}
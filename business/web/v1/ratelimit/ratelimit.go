@@ -0,0 +1,109 @@
+// Package ratelimit provides support for token bucket rate limiting with
+// pluggable storage so limits can be shared between instances.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit represents a token bucket that holds up to Burst tokens and is
+// refilled with Requests tokens every Period. Every request takes a token.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// PerMinute constructs a limit allowing n requests a minute with bursts of
+// up to n requests.
+func PerMinute(n int) Limit {
+	return Limit{
+		Requests: n,
+		Period:   time.Minute,
+		Burst:    n,
+	}
+}
+
+// rate returns the number of tokens added to the bucket every second.
+func (l Limit) rate() float64 {
+	if l.Period <= 0 {
+		return 0
+	}
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Bucket represents the state of a token bucket.
+type Bucket struct {
+	Tokens      float64
+	DateUpdated time.Time
+}
+
+// Result represents the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Take refills the bucket for the time elapsed since it was last updated
+// and takes a token from it when one is available. A zero value bucket is
+// treated as full. It returns the new state of the bucket to be stored.
+func (l Limit) Take(b Bucket, now time.Time) (Bucket, Result) {
+	burst := float64(l.Burst)
+	rate := l.rate()
+
+	tokens := burst
+	if !b.DateUpdated.IsZero() {
+		elapsed := now.Sub(b.DateUpdated).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(burst, b.Tokens+elapsed*rate)
+	}
+
+	res := Result{
+		Limit: l.Burst,
+	}
+
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.duration(1-tokens, rate)
+	}
+
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = l.duration(burst-tokens, rate)
+
+	nb := Bucket{
+		Tokens:      tokens,
+		DateUpdated: now,
+	}
+
+	return nb, res
+}
+
+// duration returns how long it takes to add the number of tokens to the
+// bucket, rounded up to the second.
+func (l Limit) duration(tokens float64, rate float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if rate == 0 {
+		return l.Period
+	}
+
+	return time.Duration(math.Ceil(tokens/rate)) * time.Second
+}
+
+// =============================================================================
+
+// Storer interface declares the behavior this package needs to persist and
+// retrieve token buckets. Take must be atomic for a given key.
+type Storer interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
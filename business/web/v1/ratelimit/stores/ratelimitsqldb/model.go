@@ -0,0 +1,32 @@
+package ratelimitsqldb
+
+import (
+	"time"
+
+	"github.com/hpetrov29/restapi/business/web/v1/ratelimit"
+)
+
+// dbBucket represent the structure we need for moving data
+// between the app and the database.
+type dbBucket struct {
+	Key         string    `db:"bucket_key"`
+	Tokens      float64   `db:"tokens"`
+	DateUpdated time.Time `db:"date_updated"`
+	DateFull    time.Time `db:"date_full"`
+}
+
+func toDBBucket(key string, b ratelimit.Bucket, dateFull time.Time) dbBucket {
+	return dbBucket{
+		Key:         key,
+		Tokens:      b.Tokens,
+		DateUpdated: b.DateUpdated.UTC(),
+		DateFull:    dateFull.UTC(),
+	}
+}
+
+func toCoreBucket(dbB dbBucket) ratelimit.Bucket {
+	return ratelimit.Bucket{
+		Tokens:      dbB.Tokens,
+		DateUpdated: dbB.DateUpdated.In(time.Local),
+	}
+}
@@ -0,0 +1,154 @@
+// Package ratelimitsqldb contains rate limit bucket related CRUD
+// functionality, allowing limits to be shared between instances.
+package ratelimitsqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	db "github.com/hpetrov29/restapi/business/data/dbsql/mysql"
+	"github.com/hpetrov29/restapi/business/web/v1/ratelimit"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Set of values controlling the maintenance of the buckets table.
+const (
+	sweepInterval = time.Minute
+	maxAttempts   = 3
+)
+
+// Store manages the set of APIs for rate limit database access.
+type Store struct {
+	log *logger.Logger
+	db  *sqlx.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log:       log,
+		db:        db,
+		lastSweep: time.Now(),
+	}
+}
+
+// Take takes a token from the bucket identified by the key. Concurrent
+// upserts of the same bucket can deadlock, in which case the database rolls
+// one of them back and the take is tried again.
+func (s *Store) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.sweep(ctx)
+
+	for attempt := 1; ; attempt++ {
+		res, err := s.take(ctx, key, limit)
+		if err == nil || !errors.Is(err, db.ErrDBDeadlock) || attempt == maxAttempts {
+			return res, err
+		}
+	}
+}
+
+// take takes a token from the bucket in a transaction. The bucket row is
+// locked for the duration of the transaction so concurrent requests from
+// every instance are serialized.
+func (s *Store) take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("begintxx: %w", err)
+	}
+	defer tx.Rollback()
+
+	bucket, err := s.queryForUpdate(ctx, tx, key)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	now := time.Now()
+	bucket, res := limit.Take(bucket, now)
+
+	const q = `
+	INSERT INTO rate_limits
+		(bucket_key, tokens, date_updated, date_full)
+	VALUES
+		(:bucket_key, :tokens, :date_updated, :date_full)
+	ON DUPLICATE KEY UPDATE
+		tokens = VALUES(tokens),
+		date_updated = VALUES(date_updated),
+		date_full = VALUES(date_full)`
+
+	if _, err := db.NamedExecContext(ctx, s.log, tx, q, toDBBucket(key, bucket, now.Add(res.Reset))); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("commit: %w", err)
+	}
+
+	return res, nil
+}
+
+// queryForUpdate gets and locks the bucket for the key. A missing bucket is
+// returned as the zero value, which is treated as full.
+func (s *Store) queryForUpdate(ctx context.Context, tx sqlx.ExtContext, key string) (ratelimit.Bucket, error) {
+	data := struct {
+		Key string `db:"bucket_key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	SELECT
+		bucket_key, tokens, date_updated, date_full
+	FROM
+		rate_limits
+	WHERE
+		bucket_key = :bucket_key
+	FOR UPDATE`
+
+	var dbB dbBucket
+	if err := db.NamedQueryStruct(ctx, s.log, tx, q, data, &dbB); err != nil {
+		if errors.Is(err, db.ErrDBNotFound) {
+			return ratelimit.Bucket{}, nil
+		}
+		return ratelimit.Bucket{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreBucket(dbB), nil
+}
+
+// sweep deletes the buckets which have refilled since they were last used,
+// as they are equivalent to a missing bucket. Every instance sweeps at most
+// once per interval. A failure only delays the purge, so it doesn't fail the
+// take.
+func (s *Store) sweep(ctx context.Context) {
+	now := time.Now()
+
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	data := struct {
+		Now time.Time `db:"now"`
+	}{
+		Now: now.UTC(),
+	}
+
+	const q = `
+	DELETE FROM
+		rate_limits
+	WHERE
+		date_full < :now`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		s.log.Error(ctx, "ratelimit: sweep failed", "msg", err)
+	}
+}
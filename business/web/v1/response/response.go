@@ -1,5 +1,13 @@
 package response
 
+import "errors"
+
+// ErrorDocument is the form used for API responses from failures in the API.
+type ErrorDocument struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
 // Error is used to pass an error during the request through the
 // application with web specific context.
 type Error struct {
@@ -13,8 +21,29 @@ func (re *Error) Error() string {
 	return re.Err.Error()
 }
 
+// Unwrap returns the wrapped error so it can be inspected with errors.Is
+// and errors.As.
+func (re *Error) Unwrap() error {
+	return re.Err
+}
+
 // NewError wraps a provided error with an HTTP status code. This
 // function should be used when handlers encounter expected errors.
 func NewError(err error, status int) error {
 	return &Error{err, status}
-}
\ No newline at end of file
+}
+
+// IsError checks if an error of type Error exists.
+func IsError(err error) bool {
+	var re *Error
+	return errors.As(err, &re)
+}
+
+// GetError returns a copy of the Error pointer.
+func GetError(err error) *Error {
+	var re *Error
+	if !errors.As(err, &re) {
+		return nil
+	}
+	return re
+}
@@ -0,0 +1,23 @@
+package session
+
+import (
+	"context"
+)
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// key is used to store/retrieve a Session value from a context.Context.
+const sessionKey ctxKey = 1
+
+// Set stores the session in the context.
+func Set(ctx context.Context, s Session) context.Context {
+	return context.WithValue(ctx, sessionKey, s)
+}
+
+// Get returns the session from the context. The boolean is false when the
+// request wasn't authenticated with a session.
+func Get(ctx context.Context) (Session, bool) {
+	s, ok := ctx.Value(sessionKey).(Session)
+	return s, ok
+}
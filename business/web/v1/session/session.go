@@ -0,0 +1,228 @@
+// Package session provides support for server side browser sessions backed
+// by an HttpOnly cookie.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hpetrov29/restapi/business/core/user"
+)
+
+// Set of error variables for session handling.
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrExpired  = errors.New("session expired")
+)
+
+// Session represents a browser session of an authenticated user.
+type Session struct {
+	Hash        []byte
+	UserID      uuid.UUID
+	Roles       []user.Role
+	CSRFToken   string
+	DateCreated time.Time
+	DateExpires time.Time
+}
+
+// Storer interface declares the behavior required to persist sessions.
+type Storer interface {
+	Create(ctx context.Context, s Session) error
+	QueryByHash(ctx context.Context, hash []byte) (Session, error)
+	UpdateExpiry(ctx context.Context, hash []byte, expires time.Time) error
+	Delete(ctx context.Context, hash []byte) error
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// Config represents the settings of the session cookie and its lifetime.
+type Config struct {
+	CookieName string
+	IdleTTL    time.Duration
+	MaxAge     time.Duration
+	Secure     bool
+	SameSite   http.SameSite
+	Domain     string
+}
+
+// Manager creates, validates and destroys sessions. Expiration is sliding:
+// every use of a session extends it by IdleTTL, up to MaxAge after it was
+// created.
+type Manager struct {
+	storer Storer
+	cfg    Config
+}
+
+// NewManager constructs a session manager.
+func NewManager(st Storer, cfg Config) *Manager {
+	return &Manager{
+		storer: st,
+		cfg:    cfg,
+	}
+}
+
+// CookieName returns the name of the cookie holding the session id.
+func (m *Manager) CookieName() string {
+	return m.cfg.CookieName
+}
+
+// Create starts a new session for the user and writes the session cookie.
+func (m *Manager) Create(ctx context.Context, w http.ResponseWriter, userID uuid.UUID, roles []user.Role) (Session, error) {
+	id, err := randomToken()
+	if err != nil {
+		return Session{}, fmt.Errorf("generating session id: %w", err)
+	}
+
+	csrf, err := randomToken()
+	if err != nil {
+		return Session{}, fmt.Errorf("generating csrf token: %w", err)
+	}
+
+	now := time.Now()
+
+	s := Session{
+		Hash:        hashID(id),
+		UserID:      userID,
+		Roles:       roles,
+		CSRFToken:   csrf,
+		DateCreated: now,
+		DateExpires: m.expiry(now, now),
+	}
+
+	if err := m.storer.Create(ctx, s); err != nil {
+		return Session{}, fmt.Errorf("create: %w", err)
+	}
+
+	m.setCookie(w, id, s.DateExpires)
+
+	return s, nil
+}
+
+// Authenticate validates the session cookie of the request and slides the
+// session expiration forward.
+func (m *Manager) Authenticate(ctx context.Context, w http.ResponseWriter, r *http.Request) (Session, error) {
+	cookie, err := r.Cookie(m.cfg.CookieName)
+	if err != nil {
+		return Session{}, ErrNotFound
+	}
+
+	hash := hashID(cookie.Value)
+
+	s, err := m.storer.QueryByHash(ctx, hash)
+	if err != nil {
+		return Session{}, fmt.Errorf("querybyhash: %w", err)
+	}
+
+	now := time.Now()
+
+	if now.After(s.DateExpires) {
+		if err := m.storer.Delete(ctx, hash); err != nil {
+			return Session{}, fmt.Errorf("delete: %w", err)
+		}
+		m.clearCookie(w)
+		return Session{}, ErrExpired
+	}
+
+	// Only extend the session once half of the idle period has passed to
+	// avoid a write on every request.
+	expires := m.expiry(s.DateCreated, now)
+	if expires.Sub(s.DateExpires) > m.cfg.IdleTTL/2 {
+		if err := m.storer.UpdateExpiry(ctx, hash, expires); err != nil {
+			return Session{}, fmt.Errorf("updateexpiry: %w", err)
+		}
+		s.DateExpires = expires
+		m.setCookie(w, cookie.Value, expires)
+	}
+
+	return s, nil
+}
+
+// Destroy ends the session of the request and clears the session cookie.
+func (m *Manager) Destroy(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	m.clearCookie(w)
+
+	cookie, err := r.Cookie(m.cfg.CookieName)
+	if err != nil {
+		return nil
+	}
+
+	if err := m.storer.Delete(ctx, hashID(cookie.Value)); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUser ends every session of the user.
+func (m *Manager) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	if err := m.storer.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("deletebyuser: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// expiry calculates the expiration of a session created at the specified
+// time when it is used now.
+func (m *Manager) expiry(created time.Time, now time.Time) time.Time {
+	expires := now.Add(m.cfg.IdleTTL)
+
+	if m.cfg.MaxAge > 0 {
+		if limit := created.Add(m.cfg.MaxAge); expires.After(limit) {
+			expires = limit
+		}
+	}
+
+	return expires
+}
+
+func (m *Manager) setCookie(w http.ResponseWriter, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   m.cfg.Domain,
+		Expires:  expires,
+		MaxAge:   int(time.Until(expires).Seconds()),
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: m.cfg.SameSite,
+	})
+}
+
+func (m *Manager) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Value:    "",
+		Path:     "/",
+		Domain:   m.cfg.Domain,
+		MaxAge:   -1,
+		Secure:   m.cfg.Secure,
+		HttpOnly: true,
+		SameSite: m.cfg.SameSite,
+	})
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashID produces the value stored in place of the raw session id, so a
+// leaked sessions table can't be used to hijack sessions.
+func hashID(id string) []byte {
+	sum := sha256.Sum256([]byte(id))
+	return sum[:]
+}
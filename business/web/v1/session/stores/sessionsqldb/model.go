@@ -0,0 +1,60 @@
+package sessionsqldb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hpetrov29/restapi/business/core/user"
+	"github.com/hpetrov29/restapi/business/data/dbsql/mysql/dbarray"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
+)
+
+// dbSession represent the structure we need for moving data
+// between the app and the database.
+type dbSession struct {
+	Hash        []byte         `db:"session_hash"`
+	UserID      uuid.UUID      `db:"user_id"`
+	Roles       dbarray.String `db:"roles"`
+	CSRFToken   string         `db:"csrf_token"`
+	DateCreated time.Time      `db:"date_created"`
+	DateExpires time.Time      `db:"date_expires"`
+}
+
+func toDBSession(s session.Session) dbSession {
+	roles := make([]string, len(s.Roles))
+	for i, role := range s.Roles {
+		roles[i] = role.Name()
+	}
+
+	return dbSession{
+		Hash:        s.Hash,
+		UserID:      s.UserID,
+		Roles:       roles,
+		CSRFToken:   s.CSRFToken,
+		DateCreated: s.DateCreated.UTC(),
+		DateExpires: s.DateExpires.UTC(),
+	}
+}
+
+func toCoreSession(dbSess dbSession) (session.Session, error) {
+	roles := make([]user.Role, len(dbSess.Roles))
+	for i, value := range dbSess.Roles {
+		var err error
+		roles[i], err = user.ParseRole(value)
+		if err != nil {
+			return session.Session{}, fmt.Errorf("parse role: %w", err)
+		}
+	}
+
+	s := session.Session{
+		Hash:        dbSess.Hash,
+		UserID:      dbSess.UserID,
+		Roles:       roles,
+		CSRFToken:   dbSess.CSRFToken,
+		DateCreated: dbSess.DateCreated.In(time.Local),
+		DateExpires: dbSess.DateExpires.In(time.Local),
+	}
+
+	return s, nil
+}
@@ -0,0 +1,143 @@
+// Package sessionsqldb contains session related CRUD functionality.
+package sessionsqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/hpetrov29/restapi/business/data/dbsql/mysql"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
+	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store manages the set of APIs for session database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new session into the database.
+func (s *Store) Create(ctx context.Context, sess session.Session) error {
+	const q = `
+	INSERT INTO sessions
+		(session_hash, user_id, roles, csrf_token, date_created, date_expires)
+	VALUES
+		(:session_hash, :user_id, :roles, :csrf_token, :date_created, :date_expires)`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, toDBSession(sess)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByHash gets the specified session from the database.
+func (s *Store) QueryByHash(ctx context.Context, hash []byte) (session.Session, error) {
+	data := struct {
+		Hash []byte `db:"session_hash"`
+	}{
+		Hash: hash,
+	}
+
+	const q = `
+	SELECT
+		session_hash, user_id, roles, csrf_token, date_created, date_expires
+	FROM
+		sessions
+	WHERE
+		session_hash = :session_hash`
+
+	var dbSess dbSession
+	if err := db.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSess); err != nil {
+		if errors.Is(err, db.ErrDBNotFound) {
+			return session.Session{}, fmt.Errorf("namedquerystruct: %w", session.ErrNotFound)
+		}
+		return session.Session{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	sess, err := toCoreSession(dbSess)
+	if err != nil {
+		return session.Session{}, err
+	}
+
+	return sess, nil
+}
+
+// UpdateExpiry sets a new expiration for the specified session.
+func (s *Store) UpdateExpiry(ctx context.Context, hash []byte, expires time.Time) error {
+	data := struct {
+		Hash        []byte    `db:"session_hash"`
+		DateExpires time.Time `db:"date_expires"`
+	}{
+		Hash:        hash,
+		DateExpires: expires.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		sessions
+	SET
+		date_expires = :date_expires
+	WHERE
+		session_hash = :session_hash`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the specified session from the database.
+func (s *Store) Delete(ctx context.Context, hash []byte) error {
+	data := struct {
+		Hash []byte `db:"session_hash"`
+	}{
+		Hash: hash,
+	}
+
+	const q = `
+	DELETE FROM
+		sessions
+	WHERE
+		session_hash = :session_hash`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByUser removes every session of the specified user from the database.
+func (s *Store) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		sessions
+	WHERE
+		user_id = :user_id`
+
+	if _, err := db.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
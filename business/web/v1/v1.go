@@ -1,34 +1,104 @@
 package v1
 
 import (
+	"fmt"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/hpetrov29/restapi/business/core/user"
 	"github.com/hpetrov29/restapi/business/web/v1/auth"
+	"github.com/hpetrov29/restapi/business/web/v1/idempotency"
+	"github.com/hpetrov29/restapi/business/web/v1/middleware"
+	"github.com/hpetrov29/restapi/business/web/v1/ratelimit"
+	"github.com/hpetrov29/restapi/business/web/v1/session"
+	"github.com/hpetrov29/restapi/internal/health"
 	"github.com/hpetrov29/restapi/internal/logger"
+	"github.com/hpetrov29/restapi/internal/mailer"
 	"github.com/hpetrov29/restapi/internal/web"
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // APIMuxConfig contains all mandatory systems required by handlers.
 type APIMuxConfig struct {
-	Build    string
-	Shutdown chan os.Signal
-	Log      *logger.Logger
-	Auth	*auth.Auth
-	DB       *sqlx.DB
+	Build          string
+	Shutdown       chan os.Signal
+	Log            *logger.Logger
+	Auth           *auth.Auth
+	Sessions       *session.Manager
+	DB             *sqlx.DB
+	Hasher         user.PasswordHasher
+	Policy         user.PasswordPolicy
+	Mailer         mailer.Mailer
+	Tokens         TokenConfig
+	Unverified     user.UnverifiedPolicy
+	AccessLog      middleware.LoggerConfig
+	Health         *health.Registry
+	Tracer         trace.Tracer
+	RateLimiter    ratelimit.Storer
+	CORS           middleware.CORSConfig
+	Idempotency    idempotency.Storer
+	IdempotencyTTL time.Duration
+	MaxBodySize    int64
+	WriteTimeout   time.Duration
+}
+
+// TokenConfig contains the settings for the single use tokens mailed to users.
+type TokenConfig struct {
+	ResetTTL  time.Duration
+	ResetURL  string
+	VerifyTTL time.Duration
+	VerifyURL string
+	Cooldown  time.Duration
+
+	ImpersonateTTL time.Duration
 }
 
 // RouteAdder defines behavior that sets the routes to bind for an instance
-// of the service.
+// of the service. Routes are added to a group so the same adder can be
+// mounted under any prefix.
 type RouteAdder interface {
-	Add(app *web.App, cfg APIMuxConfig)
+	Add(group *web.Group, cfg APIMuxConfig)
+}
+
+// Mount binds the routes of a RouteAdder under an API version, so several
+// versions of the API can be served side by side.
+type Mount struct {
+	Version string
+	Routes  RouteAdder
 }
 
-func NewAPIMux(config APIMuxConfig, routeAdder RouteAdder) http.Handler {
-	app := web.NewApp(config.Shutdown, nil)
+// NewAPIMux constructs a http.Handler with all application routes of the
+// mounted API versions bound.
+func NewAPIMux(config APIMuxConfig, mounts ...Mount) (http.Handler, error) {
+	app := web.NewApp(
+		config.Shutdown,
+		config.Tracer,
+		middleware.Logger(config.Log, config.AccessLog),
+		middleware.Metrics(),
+		middleware.Compress(middleware.DefaultCompressMinSize),
+		middleware.Errors(config.Log),
+		middleware.Panics(),
+	)
 
-	routeAdder.Add(app, config)
+	// Without allowed origins CORS is disabled, so browsers refuse every
+	// cross origin request.
+	if len(config.CORS.AllowedOrigins) > 0 {
+		cors, err := middleware.CORS(config.CORS)
+		if err != nil {
+			return nil, fmt.Errorf("cors: %w", err)
+		}
 
-	return app
-}
\ No newline at end of file
+		app.EnableCORS(cors)
+	}
+
+	app.SetMaxBodySize(config.MaxBodySize)
+	app.SetWriteTimeout(config.WriteTimeout)
+
+	for _, m := range mounts {
+		m.Routes.Add(app.Group("/"+m.Version), config)
+	}
+
+	return app, nil
+}
@@ -0,0 +1,149 @@
+// Package health provides a registry of checks used to report whether the
+// service and the dependencies it relies on are able to handle traffic.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrShuttingDown is reported once the service has started to shut down.
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// Set of statuses a check or report can have.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc reports the health of a single dependency. A nil error means
+// the dependency is healthy.
+type CheckFunc func(ctx context.Context) error
+
+// Result represents the outcome of a single check. The error of a failing
+// check is left out of the JSON representation, it can name hosts and other
+// details of the dependency which mustn't be published, so it should only be
+// logged.
+type Result struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Error   string `json:"-"`
+	Latency string `json:"latency"`
+}
+
+// Report represents the outcome of running every registered check.
+type Report struct {
+	Status string   `json:"status"`
+	Error  string   `json:"error,omitempty"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready reports whether every check passed.
+func (r Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Registry holds the set of checks that decide whether the service is ready
+// to receive traffic.
+type Registry struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+// New constructs a registry where every check is given at most timeout to
+// complete.
+func New(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout: timeout,
+	}
+}
+
+// Register adds a named check to the registry.
+func (r *Registry) Register(name string, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, check{name: name, fn: fn})
+}
+
+// Shutdown marks the service as shutting down. Every report produced after
+// this call is failing so load balancers stop routing traffic to the service
+// while in flight requests drain.
+func (r *Registry) Shutdown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs every registered check concurrently and reports the outcome.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make([]check, len(r.checks))
+	copy(checks, r.checks)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(checks))
+
+	for i, c := range checks {
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: results,
+	}
+
+	for _, result := range results {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	if r.shuttingDown.Load() {
+		report.Status = StatusDown
+		report.Error = ErrShuttingDown.Error()
+	}
+
+	return report
+}
+
+// run executes a single check within the timeout of the registry.
+func (r *Registry) run(ctx context.Context, c check) Result {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := c.fn(ctx)
+
+	result := Result{
+		Name:    c.name,
+		Status:  StatusUp,
+		Latency: time.Since(start).String(),
+	}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -93,10 +93,20 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 	if err := fs.WalkDir(fsys, ".", fn); err != nil {
 		return nil, fmt.Errorf("walking directory: %w", err)
 	}
-	
+
 	return ks, nil
 }
 
+// KeyIDs returns the ids of the keys held by the key store.
+func (ks *KeyStore) KeyIDs() []string {
+	kids := make([]string, 0, len(ks.store))
+	for kid := range ks.store {
+		kids = append(kids, kid)
+	}
+
+	return kids
+}
+
 // PrivateKey searches the key store for a given kid and returns the private key.
 func (ks *KeyStore) PrivateKey(kid string) (string, error) {
 	privateKey, found := ks.store[kid]
@@ -130,4 +140,4 @@ func (ks *KeyStore) PublicKey(kid string) (string, error) {
 	}
 
 	return b.String(), nil
-}
\ No newline at end of file
+}
@@ -16,7 +16,7 @@ type TraceIDFunc func(ctx context.Context) string
 
 // Logger is responsible for logging the messages
 type Logger struct {
-	handler slog.Handler
+	handler     slog.Handler
 	traceIDFunc TraceIDFunc
 }
 
@@ -25,11 +25,26 @@ func NewWithEvents(w io.Writer, minLevel Level, serviceName string, traceIDFunc
 	return new(w, minLevel, serviceName, traceIDFunc, events)
 }
 
+// Debug logs at LevelDebug with the given context.
+func (log *Logger) Debug(ctx context.Context, message string, args ...any) {
+	log.write(ctx, LevelDebug, 3, message, args...)
+}
+
 // Info logs at LevelInfo with the given context.
 func (log *Logger) Info(ctx context.Context, message string, args ...any) {
 	log.write(ctx, LevelInfo, 3, message, args...)
 }
 
+// Warn logs at LevelWarn with the given context.
+func (log *Logger) Warn(ctx context.Context, message string, args ...any) {
+	log.write(ctx, LevelWarn, 3, message, args...)
+}
+
+// Error logs at LevelError with the given context.
+func (log *Logger) Error(ctx context.Context, message string, args ...any) {
+	log.write(ctx, LevelError, 3, message, args...)
+}
+
 // Infoc logs the information at the specified call stack position.
 func (log *Logger) Infoc(ctx context.Context, caller int, msg string, args ...any) {
 	log.write(ctx, LevelInfo, caller, msg, args...)
@@ -86,8 +101,7 @@ func new(w io.Writer, minLevel Level, serviceName string, traceIDFunc TraceIDFun
 	handler = handler.WithAttrs(attributes)
 
 	return &Logger{
-		handler: handler,
+		handler:     handler,
 		traceIDFunc: traceIDFunc,
 	}
 }
-
@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File writes every message as an .eml file inside of a directory. It is
+// meant for local development where no SMTP relay is available.
+type File struct {
+	dir  string
+	from mail.Address
+}
+
+// NewFile constructs a mailer that writes messages into the directory,
+// creating it if needed.
+func NewFile(dir string, from mail.Address) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating mail directory: %w", err)
+	}
+
+	return &File{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send writes the message to a new file.
+func (f *File) Send(ctx context.Context, msg Message) error {
+	var suffix [4]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return fmt.Errorf("generating file name: %w", err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), hex.EncodeToString(suffix[:]))
+
+	if err := os.WriteFile(filepath.Join(f.dir, name), compose(f.from, msg, now), 0o600); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps every message in memory. It is meant for tests which need to
+// inspect what would have been delivered.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemory constructs an empty in memory mailer.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send records the message.
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msgs := make([]Message, len(m.messages))
+	copy(msgs, m.messages)
+
+	return msgs
+}
@@ -0,0 +1,229 @@
+// Package openapi builds an OpenAPI 3.1 document from the routes registered
+// on a web.App.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hpetrov29/restapi/internal/web"
+)
+
+// Version is the version of the OpenAPI specification documents conform to.
+const Version = "3.1.0"
+
+// Config contains the settings of the generated document.
+type Config struct {
+	Title       string
+	Version     string
+	Description string
+
+	// Error is the model of the body of every error response.
+	Error any
+
+	// SecuritySchemes are the ways a client can authenticate. Routes which
+	// require a rule without naming schemes accept any of DefaultSecurity.
+	SecuritySchemes map[string]SecurityScheme
+	DefaultSecurity []string
+}
+
+// Document is the root object of an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info provides metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path keyed by lower case method.
+type PathItem map[string]*Operation
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Rule        string                `json:"x-auth-rule,omitempty"`
+}
+
+// Parameter describes a single operation parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes a request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType provides the schema of a body in one representation.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable objects of the document.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme defines a security scheme operations can use.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Build generates the document describing the routes.
+func Build(cfg Config, routes []*web.Route) Document {
+	gen := newGenerator()
+
+	doc := Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       cfg.Title,
+			Version:     cfg.Version,
+			Description: cfg.Description,
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			SecuritySchemes: cfg.SecuritySchemes,
+		},
+	}
+
+	var errSchema *Schema
+	if cfg.Error != nil {
+		errSchema = gen.schemaOf(reflect.TypeOf(cfg.Error))
+	}
+
+	for _, rt := range routes {
+		if rt.Method == http.MethodOptions {
+			continue
+		}
+
+		op := Operation{
+			OperationID: operationID(rt.Method, rt.Path),
+			Summary:     rt.Summary,
+			Tags:        rt.Tags,
+			Responses:   make(map[string]Response),
+			Rule:        rt.Rule,
+		}
+
+		for _, name := range rt.Params() {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+
+		if rt.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  content(decodable, gen.schemaOf(rt.Request)),
+			}
+		}
+
+		for _, resp := range rt.Responses {
+			r := Response{
+				Description: http.StatusText(resp.Status),
+			}
+			if resp.Type != nil {
+				mediaTypes := resp.MediaTypes
+				if len(mediaTypes) == 0 {
+					mediaTypes = web.MediaTypes()
+				}
+				r.Content = content(mediaTypes, gen.schemaOf(resp.Type))
+			}
+			op.Responses[strconv.Itoa(resp.Status)] = r
+		}
+
+		if errSchema != nil {
+			op.Responses["default"] = Response{
+				Description: "Error",
+				Content:     content([]string{web.MediaTypeJSON}, errSchema),
+			}
+		}
+
+		if len(op.Responses) == 0 {
+			op.Responses["default"] = Response{Description: "Response"}
+		}
+
+		schemes := rt.Security
+		if len(schemes) == 0 && rt.Rule != "" {
+			schemes = cfg.DefaultSecurity
+		}
+		for _, scheme := range schemes {
+			op.Security = append(op.Security, map[string][]string{scheme: {}})
+		}
+
+		item, ok := doc.Paths[rt.Path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[rt.Path] = item
+		}
+		item[strings.ToLower(rt.Method)] = &op
+	}
+
+	doc.Components.Schemas = gen.schemas
+
+	return doc
+}
+
+// decodable are the media types listed for request bodies. Decode accepts
+// more, these are the ones worth advertising.
+var decodable = []string{web.MediaTypeJSON, web.MediaTypeCBOR, web.MediaTypeMsgPack, web.MediaTypeXML}
+
+// content returns the same schema for each media type.
+func content(mediaTypes []string, schema *Schema) map[string]MediaType {
+	m := make(map[string]MediaType, len(mediaTypes))
+	for _, mt := range mediaTypes {
+		m[mt] = MediaType{Schema: schema}
+	}
+	return m
+}
+
+// operationID derives a unique identifier for an operation from its method
+// and path, for example getV1UsersUserId for GET /v1/users/{user_id}.
+func operationID(method string, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+
+	words := strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		b.WriteString(strings.ToUpper(w[:1]))
+		b.WriteString(w[1:])
+	}
+
+	return b.String()
+}
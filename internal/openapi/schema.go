@@ -0,0 +1,310 @@
+package openapi
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema 2020-12 object as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// generator converts Go types into schemas, collecting named struct types
+// as reusable components.
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+	}
+}
+
+// schemaOf returns the schema of the type. Named structs are added to the
+// components and referenced.
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 && t.Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schemaOf(t.Elem()))
+
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		name := t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			// Reserve the name first so recursive types terminate.
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+// structSchema returns the object schema of the struct type, using the JSON
+// names of the fields and the constraints of their validate tags.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	names := jsonNames(t)
+
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		name, ok := names[fld.Name]
+		if !ok {
+			continue
+		}
+
+		if fld.Anonymous && fld.Type.Kind() == reflect.Struct && fld.Tag.Get("json") == "" {
+			embedded := g.structSchema(fld.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		prop := g.schemaOf(fld.Type)
+		if applyValidate(prop, fld.Tag.Get("validate"), names) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+
+	return &s
+}
+
+// jsonNames maps the exported fields of the struct to the names they have
+// in JSON documents. Fields left out of JSON aren't in the map.
+func jsonNames(t reflect.Type) map[string]string {
+	names := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !fld.IsExported() && !fld.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(fld.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = fld.Name
+		}
+
+		names[fld.Name] = name
+	}
+	return names
+}
+
+// applyValidate adds the constraints of a validate tag to the schema and
+// reports whether the tag makes the field required. Constraints following
+// dive apply to the items of a slice.
+func applyValidate(s *Schema, tag string, names map[string]string) bool {
+	if tag == "" {
+		return false
+	}
+
+	var required bool
+
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			if target == s {
+				required = true
+			}
+
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+
+		case "email":
+			target.Format = "email"
+		case "url", "uri":
+			target.Format = "uri"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		case "ip":
+			target.Format = "ip"
+		case "datetime":
+			target.Format = "date-time"
+		case "alpha":
+			target.Pattern = "^[a-zA-Z]+$"
+		case "alphanum":
+			target.Pattern = "^[a-zA-Z0-9]+$"
+		case "numeric":
+			target.Pattern = "^[-+]?[0-9]+(?:\\.[0-9]+)?$"
+
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				target.Enum = append(target.Enum, v)
+			}
+
+		case "min", "gte":
+			setBound(target, param, true, false)
+		case "max", "lte":
+			setBound(target, param, false, false)
+		case "gt":
+			setBound(target, param, true, true)
+		case "lt":
+			setBound(target, param, false, true)
+		case "len":
+			setBound(target, param, true, false)
+			setBound(target, param, false, false)
+
+		case "eqfield":
+			other := names[param]
+			if other == "" {
+				other = param
+			}
+			target.Description = "Must be equal to " + other + "."
+		}
+	}
+
+	return required
+}
+
+// setBound sets a lower or upper bound on the schema. The bound is a length
+// for strings, an item count for arrays and a value for numbers.
+func setBound(s *Schema, param string, lower bool, exclusive bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	// Lengths and counts are whole numbers, so an exclusive bound moves one
+	// step inwards.
+	count := int(n)
+	switch {
+	case exclusive && lower:
+		count++
+	case exclusive:
+		count--
+	}
+
+	switch baseType(s) {
+	case "string":
+		if lower {
+			s.MinLength = &count
+		} else {
+			s.MaxLength = &count
+		}
+
+	case "array":
+		if lower {
+			s.MinItems = &count
+		} else {
+			s.MaxItems = &count
+		}
+
+	case "integer", "number":
+		switch {
+		case lower && exclusive:
+			s.ExclusiveMinimum = &n
+		case lower:
+			s.Minimum = &n
+		case exclusive:
+			s.ExclusiveMaximum = &n
+		default:
+			s.Maximum = &n
+		}
+	}
+}
+
+// baseType returns the type of the schema ignoring null.
+func baseType(s *Schema) string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []string:
+		for _, v := range t {
+			if v != "null" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// nullable returns the schema extended to also allow null.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{
+			OneOf: []*Schema{s, {Type: "null"}},
+		}
+	}
+
+	if t, ok := s.Type.(string); ok {
+		s.Type = []string{t, "null"}
+	}
+
+	return s
+}
@@ -0,0 +1,381 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hpetrov29/restapi/internal/validate"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Set of media types the web package can encode and decode out of the box.
+const (
+	MediaTypeJSON    = "application/json"
+	MediaTypeCBOR    = "application/cbor"
+	MediaTypeMsgPack = "application/msgpack"
+	MediaTypeXML     = "application/xml"
+	MediaTypeCSV     = "text/csv"
+)
+
+// Set of errors used while negotiating the representation of a response.
+var (
+	ErrNotAcceptable    = errors.New("not acceptable")
+	ErrUnsupportedValue = errors.New("value can't be represented in this format")
+)
+
+// Encoder converts a value into the bytes of a response body. It returns
+// ErrUnsupportedValue when the value has no representation in its format so
+// the next acceptable format can be tried.
+type Encoder func(v any) ([]byte, error)
+
+// Decoder converts the bytes of a request body into the provided value.
+type Decoder func(data []byte, v any) error
+
+type encoderEntry struct {
+	mediaType string
+	encode    Encoder
+}
+
+// The registries are in order of preference, the first entry is used when
+// the client accepts anything.
+var (
+	codecMu  sync.RWMutex
+	encoders []encoderEntry
+	decoders = make(map[string]Decoder)
+)
+
+func init() {
+	RegisterEncoder(MediaTypeJSON, json.Marshal)
+	RegisterEncoder(MediaTypeCBOR, encodeCBOR)
+	RegisterEncoder(MediaTypeMsgPack, encodeMsgPack)
+	RegisterEncoder("application/x-msgpack", encodeMsgPack)
+	RegisterEncoder("application/vnd.msgpack", encodeMsgPack)
+	RegisterEncoder(MediaTypeXML, encodeXML)
+	RegisterEncoder("text/xml", encodeXML)
+	RegisterEncoder(MediaTypeCSV, encodeCSV)
+	RegisterEncoder(MediaTypeNDJSON, encodeNDJSON)
+
+	RegisterDecoder(MediaTypeJSON, decodeJSON)
+	RegisterDecoder(MediaTypeCBOR, decodeCBOR)
+	RegisterDecoder(MediaTypeMsgPack, decodeMsgPack)
+	RegisterDecoder("application/x-msgpack", decodeMsgPack)
+	RegisterDecoder("application/vnd.msgpack", decodeMsgPack)
+	RegisterDecoder(MediaTypeXML, decodeXML)
+	RegisterDecoder("text/xml", decodeXML)
+}
+
+// RegisterEncoder adds an encoder to the registry used by Respond, replacing
+// any encoder already registered for the media type.
+func RegisterEncoder(mediaType string, enc Encoder) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	for i := range encoders {
+		if encoders[i].mediaType == mediaType {
+			encoders[i].encode = enc
+			return
+		}
+	}
+
+	encoders = append(encoders, encoderEntry{mediaType: mediaType, encode: enc})
+}
+
+// RegisterDecoder adds a decoder to the registry used by Decode, replacing
+// any decoder already registered for the media type.
+func RegisterDecoder(mediaType string, dec Decoder) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+
+	decoders[strings.ToLower(mediaType)] = dec
+}
+
+// =============================================================================
+
+// encode converts the value with the most preferred encoder the Accept
+// header allows which can represent it.
+func encode(accept string, v any) (string, []byte, error) {
+	candidates := negotiate(accept)
+
+	for _, entry := range candidates {
+		data, err := entry.encode(v)
+		if err != nil {
+			if errors.Is(err, ErrUnsupportedValue) {
+				continue
+			}
+			return "", nil, err
+		}
+		return entry.mediaType, data, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: %s", ErrNotAcceptable, accept)
+}
+
+// acceptable reports whether at least one registered encoder satisfies the
+// Accept header.
+func acceptable(accept string) bool {
+	return len(negotiate(accept)) > 0
+}
+
+// encodable returns the registered media types able to represent values of
+// the type, most preferred first. Encoders are tried with the zero value of
+// the type, which is enough to tell the ones like CSV that only represent
+// some kinds of values.
+func encodable(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	zero := reflect.New(t).Elem().Interface()
+
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	var mediaTypes []string
+	for _, entry := range encoders {
+		if _, err := entry.encode(zero); err != nil {
+			continue
+		}
+		mediaTypes = append(mediaTypes, entry.mediaType)
+	}
+
+	return mediaTypes
+}
+
+// mediaRange is a single entry of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// negotiate returns the registered encoders the Accept header allows, most
+// preferred first. An empty header accepts every encoder.
+func negotiate(accept string) []encoderEntry {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	if strings.TrimSpace(accept) == "" {
+		return append([]encoderEntry(nil), encoders...)
+	}
+
+	ranges := parseAccept(accept)
+
+	type candidate struct {
+		entry encoderEntry
+		q     float64
+	}
+
+	var candidates []candidate
+	for _, entry := range encoders {
+		if q := quality(ranges, entry.mediaType); q > 0 {
+			candidates = append(candidates, candidate{entry: entry, q: q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	entries := make([]encoderEntry, len(candidates))
+	for i, c := range candidates {
+		entries[i] = c.entry
+	}
+
+	return entries
+}
+
+// parseAccept splits an Accept header into its media ranges. Malformed
+// entries are ignored.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		typ, subtype, found := strings.Cut(mediaType, "/")
+		if !found {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	return ranges
+}
+
+// quality returns the weight the most specific matching media range gives
+// to the media type.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		var s int
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+
+	return q
+}
+
+// decoderFor returns the decoder registered for the media type. Structured
+// syntax suffixes like application/problem+json fall back to the decoder of
+// the base format.
+func decoderFor(mediaType string) (Decoder, bool) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	if dec, ok := decoders[mediaType]; ok {
+		return dec, true
+	}
+
+	if _, suffix, found := strings.Cut(mediaType, "+"); found {
+		dec, ok := decoders["application/"+suffix]
+		return dec, ok
+	}
+
+	return nil, false
+}
+
+// decoderTypes returns the media types Decode accepts for error messages.
+func decoderTypes() string {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	types := make([]string, 0, len(decoders))
+	for mediaType := range decoders {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+
+	return strings.Join(types, ", ")
+}
+
+// =============================================================================
+
+var cborEncMode, _ = cbor.EncOptions{
+	Sort: cbor.SortCoreDeterministic,
+	Time: cbor.TimeRFC3339Nano,
+}.EncMode()
+
+var cborDecMode, _ = cbor.DecOptions{
+	DupMapKey:         cbor.DupMapKeyEnforcedAPF,
+	ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+}.DecMode()
+
+func encodeCBOR(v any) ([]byte, error) {
+	data, err := cborEncMode.Marshal(v)
+	if err != nil {
+		var ute *cbor.UnsupportedTypeError
+		if errors.As(err, &ute) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedValue, err)
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func decodeCBOR(data []byte, v any) error {
+	if len(data) == 0 {
+		return validate.NewFieldsError(bodyField, errors.New("body must not be empty"))
+	}
+
+	if err := cborDecMode.Unmarshal(data, v); err != nil {
+		var ute *cbor.UnmarshalTypeError
+		if errors.As(err, &ute) && ute.StructFieldName != "" {
+			return validate.NewFieldsError(ute.StructFieldName, fmt.Errorf("expected %s but got %s", ute.GoType, ute.CBORType))
+		}
+		return validate.NewFieldsError(bodyField, fmt.Errorf("malformed CBOR: %s", strings.TrimPrefix(err.Error(), "cbor: ")))
+	}
+
+	return nil
+}
+
+// encodeNDJSON writes each element of a slice as a line of JSON. Any other
+// value is written as a single line.
+func encodeNDJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeMsgPack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeMsgPack(data []byte, v any) error {
+	if len(data) == 0 {
+		return validate.NewFieldsError(bodyField, errors.New("body must not be empty"))
+	}
+
+	r := bytes.NewReader(data)
+
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return validate.NewFieldsError(bodyField, fmt.Errorf("malformed MessagePack: %s", strings.TrimPrefix(err.Error(), "msgpack: ")))
+	}
+
+	if r.Len() > 0 {
+		return validate.NewFieldsError(bodyField, fmt.Errorf("unexpected data after MessagePack document ending at offset %d", len(data)-r.Len()))
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		name      string
+		accept    string
+		mediaType string
+		want      float64
+	}{
+		{"exact", "application/json", MediaTypeJSON, 1},
+		{"weighted", "application/json;q=0.4", MediaTypeJSON, 0.4},
+		{"subtype wildcard", "application/*;q=0.7", MediaTypeCBOR, 0.7},
+		{"any", "*/*;q=0.2", MediaTypeXML, 0.2},
+		{"specific beats wildcard", "*/*, application/json;q=0.1", MediaTypeJSON, 0.1},
+		{"refused beats wildcard", "*/*, text/csv;q=0", MediaTypeCSV, 0},
+		{"subtype wildcard beats any", "*/*;q=0.9, text/*;q=0.3", MediaTypeCSV, 0.3},
+		{"case insensitive", "Application/JSON", MediaTypeJSON, 1},
+		{"parameters ignored", "application/json; charset=utf-8", MediaTypeJSON, 1},
+		{"no match", "text/html", MediaTypeJSON, 0},
+		{"malformed weight ignored", "application/json;q=high", MediaTypeJSON, 0},
+		{"malformed range ignored", "json, application/cbor", MediaTypeCBOR, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quality(parseAccept(tt.accept), tt.mediaType); got != tt.want {
+				t.Errorf("quality(%q, %q) = %v, want %v", tt.accept, tt.mediaType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   []string
+	}{
+		{"empty accepts all", "", registered()},
+		{"single", "application/cbor", []string{MediaTypeCBOR}},
+		{"by weight", "application/xml;q=0.5, application/cbor", []string{MediaTypeCBOR, MediaTypeXML}},
+		{"registry order on equal weight", "application/xml, application/json", []string{MediaTypeJSON, MediaTypeXML}},
+		{"subtype wildcard", "text/*", []string{"text/xml", MediaTypeCSV}},
+		{"any but refused", "*/*, application/json;q=0", without(registered(), MediaTypeJSON)},
+		{"nothing registered", "image/png", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range negotiate(tt.accept) {
+				got = append(got, entry.mediaType)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodable(t *testing.T) {
+	type item struct {
+		ID string `json:"id"`
+	}
+
+	tests := []struct {
+		name    string
+		typ     reflect.Type
+		wantCSV bool
+	}{
+		{"struct", reflect.TypeOf(item{}), false},
+		{"pointer to struct", reflect.TypeOf(&item{}), false},
+		{"slice of structs", reflect.TypeOf([]item{}), true},
+		{"slice of pointers", reflect.TypeOf([]*item{}), true},
+		{"slice of strings", reflect.TypeOf([]string{}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodable(tt.typ)
+
+			if !slices.Contains(got, MediaTypeJSON) {
+				t.Errorf("encodable(%s) = %q, want it to contain %q", tt.typ, got, MediaTypeJSON)
+			}
+			if slices.Contains(got, MediaTypeCSV) != tt.wantCSV {
+				t.Errorf("encodable(%s) = %q, want csv %v", tt.typ, got, tt.wantCSV)
+			}
+		})
+	}
+}
+
+// registered returns the media types of the registered encoders in order of
+// preference.
+func registered() []string {
+	var mediaTypes []string
+	for _, entry := range encoders {
+		mediaTypes = append(mediaTypes, entry.mediaType)
+	}
+	return mediaTypes
+}
+
+func without(mediaTypes []string, mediaType string) []string {
+	return slices.DeleteFunc(slices.Clone(mediaTypes), func(mt string) bool {
+		return mt == mediaType
+	})
+}
@@ -3,6 +3,9 @@ package web
 import (
 	"context"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ctxKey int
@@ -11,9 +14,30 @@ const key ctxKey = 1
 
 // Values represents state for each request
 type Values struct {
-	TraceId string
-	Now     time.Time
-	StatusCode int
+	TraceId      string
+	Tracer       trace.Tracer
+	Now          time.Time
+	StatusCode   int
+	MaxBodySize  int64
+	WriteTimeout time.Duration
+	Accept       string
+}
+
+// setValues stores the request values in the context.
+func setValues(ctx context.Context, v *Values) context.Context {
+	return context.WithValue(ctx, key, v)
+}
+
+// GetValues returns the values from the context.
+func GetValues(ctx context.Context) *Values {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return &Values{
+			TraceId: "00000000-0000-0000-0000-000000000000",
+			Now:     time.Now(),
+		}
+	}
+	return v
 }
 
 // GetTraceID returns the trace id from the context.
@@ -33,4 +57,18 @@ func SetStatusCode(ctx context.Context, statusCode int) {
 	}
 
 	v.StatusCode = statusCode
-}
\ No newline at end of file
+}
+
+// AddSpan adds an OpenTelemetry span to the trace and context. The span
+// must be ended by the caller.
+func AddSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyValue) (context.Context, trace.Span) {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok || v.Tracer == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	ctx, span := v.Tracer.Start(ctx, spanName)
+	span.SetAttributes(keyValues...)
+
+	return ctx, span
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// csvColumn is a struct field written as a column of a CSV document.
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// encodeCSV converts a slice of structs into a CSV document. The header row
+// uses the JSON names of the fields. Values which aren't scalars are written
+// as their JSON representation.
+func encodeCSV(v any) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: csv requires a slice, got %T", ErrUnsupportedValue, v)
+	}
+
+	elem := rv.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: csv requires a slice of structs, got %T", ErrUnsupportedValue, v)
+	}
+
+	columns := csvColumns(elem)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(csvHeader(columns)); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(columns))
+	for i := 0; i < rv.Len(); i++ {
+		if err := csvRecord(rv.Index(i), columns, record); err != nil {
+			return nil, fmt.Errorf("row[%d]: %w", i, err)
+		}
+
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// csvColumns returns the exported fields of the struct type which are part
+// of its JSON representation.
+func csvColumns(t reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !fld.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(fld.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = fld.Name
+		}
+
+		columns = append(columns, csvColumn{name: name, index: fld.Index})
+	}
+
+	return columns
+}
+
+// csvHeader returns the header row for the columns.
+func csvHeader(columns []csvColumn) []string {
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	return header
+}
+
+// csvRecord formats the row into the record, which must have one entry per
+// column.
+func csvRecord(row reflect.Value, columns []csvColumn, record []string) error {
+	row = reflect.Indirect(row)
+
+	for i, col := range columns {
+		value, err := csvValue(row, col.index)
+		if err != nil {
+			return fmt.Errorf("column[%s]: %w", col.name, err)
+		}
+		record[i] = value
+	}
+
+	return nil
+}
+
+// csvValue formats the field of the row as a CSV cell.
+func csvValue(row reflect.Value, index []int) (string, error) {
+	if !row.IsValid() {
+		return "", nil
+	}
+
+	fv := row.FieldByIndex(index)
+	for fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return "", nil
+		}
+		fv = fv.Elem()
+	}
+
+	if tm, ok := fv.Interface().(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'f', -1, fv.Type().Bits()), nil
+	}
+
+	data, err := json.Marshal(fv.Interface())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Set of headers used for conditional requests.
+const (
+	ETagHeader        = "ETag"
+	IfMatchHeader     = "If-Match"
+	IfNoneMatchHeader = "If-None-Match"
+)
+
+// etagCodingSep separates the content coding EncodedETag adds to an entity
+// tag. It isn't a valid character of a media type or a content coding, so it
+// can't be confused with a part of the tag.
+const etagCodingSep = ":"
+
+// SetETag sets the entity tag of the resource in the response.
+func SetETag(w http.ResponseWriter, etag string) {
+	w.Header().Set(ETagHeader, etag)
+}
+
+// EncodedETag returns the entity tag of a representation once it has been
+// compressed with the content coding. Strong validators must differ between
+// representations whose bytes differ, so the coding is added to the tag.
+func EncodedETag(etag string, coding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+
+	return strings.TrimSuffix(etag, `"`) + etagCodingSep + coding + `"`
+}
+
+// MatchETag reports whether the etag is listed in the value of an
+// If-None-Match header. The weak comparison is used, so a W/ prefix is
+// ignored, and * matches any etag. The content coding of the candidates is
+// ignored as well, the client may have received a compressed representation.
+func MatchETag(header string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if unencodedETag(strings.TrimPrefix(candidate, "W/")) == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchStrongETag reports whether the etag is listed in the value of an
+// If-Match header. The strong comparison RFC 9110 requires for If-Match is
+// used, so weak etags never match, and * matches any etag. Candidates are
+// compared without their content coding, which doesn't change the resource
+// the client is about to modify.
+func MatchStrongETag(header string, etag string) bool {
+	if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || unencodedETag(candidate) == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unencodedETag removes the content coding EncodedETag added to the etag.
+func unencodedETag(etag string) string {
+	i := strings.LastIndex(etag, etagCodingSep)
+	if i < 0 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+
+	return etag[:i] + `"`
+}
@@ -0,0 +1,83 @@
+package web
+
+import "testing"
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		etag   string
+		want   bool
+	}{
+		{"same strong", `"a-1"`, `"a-1"`, true},
+		{"same weak", `W/"a-1"`, `W/"a-1"`, true},
+		{"weak header strong etag", `W/"a-1"`, `"a-1"`, true},
+		{"strong header weak etag", `"a-1"`, `W/"a-1"`, true},
+		{"other version", `"a-2"`, `"a-1"`, false},
+		{"list", `"a-0", "a-1"`, `"a-1"`, true},
+		{"list without spaces", `"a-0","a-1"`, `"a-1"`, true},
+		{"compressed", `"a-1:gzip"`, `"a-1"`, true},
+		{"compressed weak", `W/"a-1:br"`, `"a-1"`, true},
+		{"compressed other version", `"a-2:gzip"`, `"a-1"`, false},
+		{"any", `*`, `"a-1"`, true},
+		{"unquoted", `a-1`, `"a-1"`, false},
+		{"empty", ``, `"a-1"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchETag(tt.header, tt.etag); got != tt.want {
+				t.Errorf("MatchETag(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchStrongETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		etag   string
+		want   bool
+	}{
+		{"same strong", `"a-1"`, `"a-1"`, true},
+		{"same weak", `W/"a-1"`, `W/"a-1"`, false},
+		{"weak header strong etag", `W/"a-1"`, `"a-1"`, false},
+		{"strong header weak etag", `"a-1"`, `W/"a-1"`, false},
+		{"other version", `"a-2"`, `"a-1"`, false},
+		{"list", `"a-0", "a-1"`, `"a-1"`, true},
+		{"any", `*`, `"a-1"`, true},
+		{"any weak etag", `*`, `W/"a-1"`, false},
+		{"compressed", `"a-1;application/json:gzip"`, `"a-1;application/json"`, true},
+		{"compressed weak", `W/"a-1:gzip"`, `"a-1"`, false},
+		{"other media type", `"a-1;application/cbor"`, `"a-1;application/json"`, false},
+		{"empty", ``, `"a-1"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchStrongETag(tt.header, tt.etag); got != tt.want {
+				t.Errorf("MatchStrongETag(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodedETag(t *testing.T) {
+	tests := []struct {
+		etag   string
+		coding string
+		want   string
+	}{
+		{`"a-1"`, "gzip", `"a-1:gzip"`},
+		{`"a-1;application/json"`, "zstd", `"a-1;application/json:zstd"`},
+		{`W/"a-1"`, "deflate", `W/"a-1:deflate"`},
+		{`a-1`, "gzip", `a-1`},
+	}
+
+	for _, tt := range tests {
+		if got := EncodedETag(tt.etag, tt.coding); got != tt.want {
+			t.Errorf("EncodedETag(%q, %q) = %q, want %q", tt.etag, tt.coding, got, tt.want)
+		}
+	}
+}
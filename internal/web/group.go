@@ -0,0 +1,52 @@
+package web
+
+import "strings"
+
+// Group is a set of routes sharing a path prefix and middlewares. Groups
+// can be nested, in which case the prefixes are joined and the middlewares
+// of the outer group run first.
+type Group struct {
+	app         *App
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group returns a group nested in this one.
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		app:         g.app,
+		prefix:      joinPath(g.prefix, prefix),
+		middlewares: appendMiddlewares(g.middlewares, middlewares),
+	}
+}
+
+// Handle sets a handler function for a given HTTP method and path, relative
+// to the prefix of the group. The middlewares run after the ones of the
+// group.
+func (g *Group) Handle(method string, path string, handler Handler, middlewares ...Middleware) *Route {
+	return g.app.route(method, joinPath(g.prefix, path), handler, appendMiddlewares(g.middlewares, middlewares))
+}
+
+// joinPath joins a prefix and a path, making sure the result starts with a
+// single slash and has no slash doubled at the seam.
+func joinPath(prefix string, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	p := prefix + path
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	return p
+}
+
+// appendMiddlewares returns a new slice with the middlewares of b after the
+// ones of a, so groups never share a backing array.
+func appendMiddlewares(a []Middleware, b []Middleware) []Middleware {
+	mw := make([]Middleware, 0, len(a)+len(b))
+	mw = append(mw, a...)
+	return append(mw, b...)
+}
@@ -1,11 +1,35 @@
 package web
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/go-chi/chi"
+	"github.com/hpetrov29/restapi/internal/validate"
+)
+
+// DefaultMaxBodySize is the largest request body Decode accepts when the
+// app hasn't been configured with a different limit.
+const DefaultMaxBodySize int64 = 1 << 20
+
+// bodyField is the field name used to report errors that concern the
+// request body as a whole rather than a single field.
+const bodyField = "body"
+
+// Set of errors returned by Decode that describe a request which can't be
+// processed at all, as opposed to one carrying invalid data.
+var (
+	ErrBodyTooLarge         = errors.New("request body too large")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrUnsupportedEncoding  = errors.New("unsupported content encoding")
 )
 
 type validator interface {
@@ -17,15 +41,16 @@ func Param(r *http.Request, key string) string {
 	return chi.URLParam(r, key)
 }
 
-// Decode reads the body of an HTTP request looking for a JSON document. The
-// body is decoded into the provided value.
+// Decode reads the body of an HTTP request and decodes it into the provided
+// value with the decoder registered for the Content-Type of the request.
+// The body can't exceed the maximum size configured on the app. Malformed
+// documents, values of the wrong type, unknown fields and data trailing the
+// document are reported as validate.FieldErrors.
 // If the provided value is a struct then it is checked for validation tags.
 // If the value implements a validate function, it is executed.
 func Decode(r *http.Request, val any) error {
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(val); err != nil {
-		return fmt.Errorf("unable to decode payload: %w", err)
+	if err := decodeBody(r, val); err != nil {
+		return err
 	}
 
 	if v, ok := val.(validator); ok {
@@ -36,3 +61,164 @@ func Decode(r *http.Request, val any) error {
 
 	return nil
 }
+
+// decodeBody decodes the body of the request into the value without
+// validating it.
+func decodeBody(r *http.Request, val any) error {
+	decode, err := requestDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	limit := GetValues(r.Context()).MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+
+	if r.ContentLength > limit {
+		return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, limit)
+	}
+
+	body, err := requestBody(r, limit)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(http.MaxBytesReader(nil, body, limit))
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, limit)
+		}
+		return validate.NewFieldsError(bodyField, fmt.Errorf("unable to read payload: %w", err))
+	}
+
+	return decode(data, val)
+}
+
+// requestDecoder returns the decoder for the Content-Type of the request.
+// Text based formats must be utf-8 encoded.
+func requestDecoder(r *http.Request) (Decoder, error) {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return nil, fmt.Errorf("%w: missing Content-Type, expected one of %s", ErrUnsupportedMediaType, decoderTypes())
+	}
+
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, ct)
+	}
+
+	decode, ok := decoderFor(mediaType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, expected one of %s", ErrUnsupportedMediaType, mediaType, decoderTypes())
+	}
+
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return nil, fmt.Errorf("%w: charset %s, expected utf-8", ErrUnsupportedMediaType, charset)
+	}
+
+	return decode, nil
+}
+
+// decodeJSON decodes a single JSON document into the value.
+func decodeJSON(data []byte, val any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(val); err != nil {
+		return decodeError(err)
+	}
+
+	end := decoder.InputOffset()
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return decodeError(err)
+		}
+		return validate.NewFieldsError(bodyField, fmt.Errorf("unexpected data after JSON document ending at offset %d", end))
+	}
+
+	return nil
+}
+
+// requestBody returns a reader over the decoded request body. The raw body
+// is limited as well as the decoded one so a compressed payload can't be
+// used to push past the limit.
+func requestBody(r *http.Request, limit int64) (io.ReadCloser, error) {
+	raw := http.MaxBytesReader(nil, r.Body, limit)
+
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return raw, nil
+
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(raw)
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				return nil, fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, limit)
+			}
+			return nil, validate.NewFieldsError(bodyField, fmt.Errorf("unable to decompress payload: %w", err))
+		}
+		return zr, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+	}
+}
+
+// decodeError converts an error returned by the JSON decoder into an error
+// the client can act on.
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return validate.NewFieldsError(bodyField, fmt.Errorf("malformed JSON at offset %d: %s", syntaxErr.Offset, strings.TrimPrefix(syntaxErr.Error(), "json: ")))
+
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = bodyField
+		}
+		return validate.NewFieldsError(field, fmt.Errorf("expected %s but got %s at offset %d", jsonType(typeErr.Type), typeErr.Value, typeErr.Offset))
+
+	case errors.Is(err, io.EOF):
+		return validate.NewFieldsError(bodyField, errors.New("body must not be empty"))
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return validate.NewFieldsError(bodyField, errors.New("JSON document is incomplete"))
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return validate.NewFieldsError(field, errors.New("unknown field"))
+	}
+
+	return fmt.Errorf("unable to decode payload: %w", err)
+}
+
+// jsonType returns the JSON name of the type a Go value is decoded from.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+
+	return t.String()
+}
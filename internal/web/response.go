@@ -3,28 +3,57 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 )
 
-// Respond converts a Go value to JSON and sends it to the client.
+// ResponseType returns the media type Respond sends the value as, so a
+// handler can tell the representations of a resource apart, for example in
+// their ETag.
+func ResponseType(ctx context.Context, data any) (string, error) {
+	mediaType, _, err := encode(GetValues(ctx).Accept, data)
+	if err != nil {
+		return "", err
+	}
+
+	return mediaType, nil
+}
+
+// Respond converts a Go value to the representation the client asked for in
+// the Accept header and sends it to the client. Error responses fall back to
+// JSON when no acceptable representation exists, so the client always learns
+// why the request failed.
 func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode int) error {
 
 	SetStatusCode(ctx, statusCode)
 
-	if statusCode == http.StatusNoContent {
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
 		w.WriteHeader(statusCode)
 		return nil
 	}
 
-	jsonData, err := json.Marshal(data)
+	mediaType, body, err := encode(GetValues(ctx).Accept, data)
 	if err != nil {
-		return err
+		if !errors.Is(err, ErrNotAcceptable) || statusCode < http.StatusBadRequest {
+			return err
+		}
+
+		mediaType = MediaTypeJSON
+		if body, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		mediaType += "; charset=utf-8"
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", mediaType)
 	w.WriteHeader(statusCode)
 
-	if _, err := w.Write(jsonData); err != nil {
+	if _, err := w.Write(body); err != nil {
 		return err
 	}
 
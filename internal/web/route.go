@@ -0,0 +1,157 @@
+package web
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Route describes a route bound to the app. It is recorded when the route is
+// added so documentation, like an OpenAPI specification, can be generated
+// from the routes actually served.
+type Route struct {
+	Method    string
+	Path      string
+	Summary   string
+	Tags      []string
+	Request   reflect.Type
+	Responses []RouteResponse
+	Rule      string
+	Security  []string
+}
+
+// RouteResponse describes one of the responses of a route. A nil Type means
+// the response has no body.
+type RouteResponse struct {
+	Status     int
+	Type       reflect.Type
+	MediaTypes []string
+}
+
+// Describe sets the one line summary of the route.
+func (rt *Route) Describe(summary string) *Route {
+	rt.Summary = summary
+	return rt
+}
+
+// Tagged adds tags used to group the route in documentation.
+func (rt *Route) Tagged(tags ...string) *Route {
+	rt.Tags = append(rt.Tags, tags...)
+	return rt
+}
+
+// Accepts sets the model the route decodes from the request body.
+func (rt *Route) Accepts(model any) *Route {
+	rt.Request = reflect.TypeOf(model)
+	return rt
+}
+
+// Returns adds a response of the route. The model is nil for responses
+// without a body. Media types default to every type Respond can encode.
+func (rt *Route) Returns(status int, model any, mediaTypes ...string) *Route {
+	var typ reflect.Type
+	if model != nil {
+		typ = reflect.TypeOf(model)
+	}
+
+	rt.Responses = append(rt.Responses, RouteResponse{
+		Status:     status,
+		Type:       typ,
+		MediaTypes: mediaTypes,
+	})
+	return rt
+}
+
+// Requires records the authorization rule enforced on the route.
+func (rt *Route) Requires(rule string) *Route {
+	rt.Rule = rule
+	return rt
+}
+
+// SecuredBy records the names of the security schemes the route accepts
+// credentials from, when they differ from the default ones.
+func (rt *Route) SecuredBy(schemes ...string) *Route {
+	rt.Security = append(rt.Security, schemes...)
+	return rt
+}
+
+// Params returns the names of the path parameters of the route.
+func (rt *Route) Params() []string {
+	var params []string
+	for _, segment := range strings.Split(rt.Path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name, _, _ := strings.Cut(segment[1:len(segment)-1], ":")
+			params = append(params, name)
+		}
+	}
+	return params
+}
+
+// offers returns the media types able to represent every successful response
+// of the route which has a body, most preferred first. Responses without
+// media types of their own can be sent in any registered format able to
+// represent their model. It returns nil when the route describes no such
+// response.
+func (rt *Route) offers() []string {
+	var offers []string
+	described := false
+
+	for _, resp := range rt.Responses {
+		if resp.Type == nil || resp.Status >= http.StatusBadRequest {
+			continue
+		}
+
+		mediaTypes := resp.MediaTypes
+		if len(mediaTypes) == 0 {
+			mediaTypes = encodable(resp.Type)
+		}
+
+		if !described {
+			offers = append([]string{}, mediaTypes...)
+			described = true
+			continue
+		}
+
+		common := offers[:0]
+		for _, mt := range offers {
+			if slices.Contains(mediaTypes, mt) {
+				common = append(common, mt)
+			}
+		}
+		offers = common
+	}
+
+	return offers
+}
+
+// =============================================================================
+
+// Routes returns the routes bound to the app in the order they were added.
+func (a *App) Routes() []*Route {
+	return append([]*Route(nil), a.routes...)
+}
+
+// Routes returns the routes bound to the app under the prefix of the group.
+func (g *Group) Routes() []*Route {
+	var routes []*Route
+	for _, rt := range g.app.routes {
+		if rt.Path == g.prefix || strings.HasPrefix(rt.Path, strings.TrimSuffix(g.prefix, "/")+"/") {
+			routes = append(routes, rt)
+		}
+	}
+	return routes
+}
+
+// MediaTypes returns the media types Respond can encode, most preferred
+// first.
+func MediaTypes() []string {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	types := make([]string, len(encoders))
+	for i, entry := range encoders {
+		types[i] = entry.mediaType
+	}
+	return types
+}
@@ -0,0 +1,189 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// MediaTypeNDJSON is the media type of newline delimited JSON streams.
+const MediaTypeNDJSON = "application/x-ndjson"
+
+// DefaultWriteTimeout is the time a stream may take to send its next values
+// when the app hasn't been configured with the write timeout of its server.
+const DefaultWriteTimeout = 10 * time.Second
+
+// Set of values controlling how often a stream is flushed to the client.
+const (
+	streamFlushEvery    = 100
+	streamFlushInterval = time.Second
+)
+
+// Negotiate returns the offer the Accept header of the request prefers, or
+// an empty string when none of the offers is acceptable. Offers are given in
+// order of preference for clients that accept anything.
+func Negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// Stream writes a sequence of values of type T to the client as they are
+// produced, so a response of any size can be sent in constant memory. The
+// stream is flushed to the client every few values and at least once a
+// second while values keep coming.
+type Stream[T any] struct {
+	ctx          context.Context
+	rc           *http.ResponseController
+	encode       func(v T) error
+	flush        func() error
+	pending      int
+	lastFlush    time.Time
+	writeTimeout time.Duration
+}
+
+// NewStream starts a streaming response with the status code, encoding the
+// values as NDJSON or, for struct types, as CSV. The response can take longer
+// than the write timeout of the server, so the write deadline of the
+// connection is moved forward by the write timeout every time values are
+// sent. A client which stops reading still fails the stream once it passes.
+func NewStream[T any](ctx context.Context, w http.ResponseWriter, mediaType string, statusCode int) (*Stream[T], error) {
+	s := Stream[T]{
+		ctx:          ctx,
+		rc:           http.NewResponseController(w),
+		lastFlush:    time.Now(),
+		writeTimeout: GetValues(ctx).WriteTimeout,
+	}
+
+	if s.writeTimeout <= 0 {
+		s.writeTimeout = DefaultWriteTimeout
+	}
+
+	switch mediaType {
+	case MediaTypeNDJSON:
+		enc := json.NewEncoder(w)
+		s.encode = func(v T) error { return enc.Encode(v) }
+		s.flush = func() error { return nil }
+
+	case MediaTypeCSV:
+		typ := reflect.TypeOf((*T)(nil)).Elem()
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%w: csv requires a struct, got %s", ErrUnsupportedValue, typ)
+		}
+
+		columns := csvColumns(typ)
+		record := make([]string, len(columns))
+		cw := csv.NewWriter(w)
+
+		s.encode = func(v T) error {
+			if err := csvRecord(reflect.ValueOf(v), columns, record); err != nil {
+				return err
+			}
+			return cw.Write(record)
+		}
+		s.flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+
+		// The csv writer is buffered so the header doesn't reach the
+		// client before the status line below.
+		if err := cw.Write(csvHeader(columns)); err != nil {
+			return nil, err
+		}
+		mediaType += "; charset=utf-8"
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNotAcceptable, mediaType)
+	}
+
+	if err := s.extendDeadline(); err != nil {
+		return nil, err
+	}
+
+	SetStatusCode(ctx, statusCode)
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(statusCode)
+
+	return &s, nil
+}
+
+// Send writes the value to the stream. It fails once the request has been
+// canceled so producers stop as soon as the client goes away.
+func (s *Stream[T]) Send(v T) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	// The encoders write to the connection once their buffers fill up.
+	if err := s.extendDeadline(); err != nil {
+		return err
+	}
+
+	if err := s.encode(v); err != nil {
+		return err
+	}
+
+	s.pending++
+	if s.pending >= streamFlushEvery || time.Since(s.lastFlush) >= streamFlushInterval {
+		return s.Flush()
+	}
+
+	return nil
+}
+
+// Flush sends the values written so far to the client.
+func (s *Stream[T]) Flush() error {
+	if err := s.extendDeadline(); err != nil {
+		return err
+	}
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	s.pending = 0
+	s.lastFlush = time.Now()
+
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
+
+// extendDeadline gives the connection another write timeout to accept the
+// data about to be written.
+func (s *Stream[T]) extendDeadline() error {
+	if err := s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("set write deadline: %w", err)
+	}
+
+	return nil
+}
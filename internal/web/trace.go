@@ -0,0 +1,93 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Set of headers used to propagate the trace id of a request.
+const (
+	TraceParentHeader = "traceparent"
+	RequestIDHeader   = "X-Request-ID"
+)
+
+// maxRequestIDLength caps the size of a client supplied request id so it
+// can't bloat the logs.
+const maxRequestIDLength = 128
+
+// traceID returns the trace id for the request. The trace id of a W3C
+// traceparent header takes precedence over an X-Request-ID header. When
+// neither carries a usable value the trace id of the request span is used so
+// logs can be correlated with traces, or a new id is generated.
+func traceID(r *http.Request, sc trace.SpanContext) string {
+	if id, ok := parseTraceParent(r.Header.Get(TraceParentHeader)); ok {
+		return id
+	}
+
+	if id := r.Header.Get(RequestIDHeader); isValidRequestID(id) {
+		return id
+	}
+
+	if sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+
+	return uuid.NewString()
+}
+
+// parseTraceParent extracts the trace id from a traceparent header value.
+// Example: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceParent(value string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return "", false
+	}
+
+	version, traceID, parentID := parts[0], parts[1], parts[2]
+
+	// Version ff is invalid and version 00 doesn't allow extra fields.
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" {
+		return "", false
+	}
+	if version == "00" && len(parts) != 4 {
+		return "", false
+	}
+
+	if len(traceID) != 32 || !isLowerHex(traceID) || strings.Count(traceID, "0") == 32 {
+		return "", false
+	}
+	if len(parentID) != 16 || !isLowerHex(parentID) {
+		return "", false
+	}
+
+	return traceID, true
+}
+
+// isValidRequestID reports whether a client supplied request id is safe to
+// log and echo back.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,283 @@
+package web
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/hpetrov29/restapi/internal/validate"
+)
+
+// NoContent is the response of typed handlers which don't return a body.
+type NoContent struct{}
+
+// StatusCoder is implemented by responses of typed handlers which choose
+// their own success status.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// JSON adapts a typed function into a Handler. The request value is built
+// from the request body and from the fields tagged with path, query or
+// header, then validated before fn is called. Bound fields never come from
+// the body.
+//
+// The success status is taken from the response when it implements
+// StatusCoder, is 204 when the response is NoContent, 201 for POST requests
+// and 200 otherwise. The response is encoded with Respond, so despite the
+// name it honors the Accept header like any other handler.
+//
+// Errors returned by fn are passed up the middleware chain unchanged.
+func JSON[Req any, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) Handler {
+	fields := boundFields(reflect.TypeOf((*Req)(nil)).Elem())
+
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var req Req
+
+		if fields.body && hasBody(r) {
+			if err := decodeBody(r, &req); err != nil {
+				return requestError(err)
+			}
+		}
+
+		if err := bind(r, &req, fields.bound); err != nil {
+			return err
+		}
+
+		if err := check(&req); err != nil {
+			return requestError(err)
+		}
+
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		status := successStatus(r, resp)
+		if status == http.StatusNoContent {
+			return Respond(ctx, w, nil, status)
+		}
+
+		return Respond(ctx, w, resp, status)
+	}
+}
+
+// =============================================================================
+
+// boundField is a struct field whose value comes from the URL or a header.
+type boundField struct {
+	index  []int
+	source string
+	name   string
+}
+
+// requestFields describes where the fields of a request type come from.
+type requestFields struct {
+	bound []boundField
+	body  bool
+}
+
+// boundFields inspects the request type once so requests don't pay for the
+// reflection on its tags.
+func boundFields(t reflect.Type) requestFields {
+	var rf requestFields
+	if t.Kind() != reflect.Struct {
+		return rf
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !fld.IsExported() {
+			continue
+		}
+
+		var found bool
+		for _, source := range []string{"path", "query", "header"} {
+			if name := fld.Tag.Get(source); name != "" {
+				rf.bound = append(rf.bound, boundField{index: fld.Index, source: source, name: name})
+				found = true
+				break
+			}
+		}
+
+		if !found && fld.Tag.Get("json") != "-" {
+			rf.body = true
+		}
+	}
+
+	return rf
+}
+
+// hasBody reports whether the request carries a body, or is expected to.
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return r.ContentLength > 0 || len(r.TransferEncoding) > 0
+}
+
+// bind sets the bound fields of the request value from the URL and headers.
+// Every field is reset first so a value can't be smuggled in the body.
+func bind(r *http.Request, req any, fields []boundField) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	rv := reflect.ValueOf(req).Elem()
+	query := r.URL.Query()
+
+	var fieldErrs validate.FieldErrors
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		fv.Set(reflect.Zero(fv.Type()))
+
+		var values []string
+		switch f.source {
+		case "path":
+			if v := Param(r, f.name); v != "" {
+				values = []string{v}
+			}
+		case "query":
+			values = query[f.name]
+		case "header":
+			values = r.Header.Values(f.name)
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setField(fv, values); err != nil {
+			fieldErrs = append(fieldErrs, validate.FieldError{Field: f.name, Err: err.Error()})
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		return fieldErrs
+	}
+
+	return nil
+}
+
+// setField converts the values into the type of the field. Slices take every
+// value, any other type takes the first one.
+func setField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(slice.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	return setValue(fv, values[0])
+}
+
+// setValue parses the string into the value.
+func setValue(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+
+	if tu, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := tu.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid value %q", s)
+		}
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+
+	case reflect.Bool:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expected boolean but got %q", s)
+		}
+		fv.SetBool(v)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected integer but got %q", s)
+		}
+		fv.SetInt(v)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected non negative integer but got %q", s)
+		}
+		fv.SetUint(v)
+
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected number but got %q", s)
+		}
+		fv.SetFloat(v)
+
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+
+	return nil
+}
+
+// check validates the request value with its Validate method, or with its
+// validate tags when it has no such method.
+func check(req any) error {
+	if v, ok := req.(validator); ok {
+		return v.Validate()
+	}
+
+	if reflect.ValueOf(req).Elem().Kind() == reflect.Struct {
+		return validate.Check(req)
+	}
+
+	return nil
+}
+
+// requestError makes sure an error caused by the request is reported to the
+// client as such rather than as an internal failure.
+func requestError(err error) error {
+	switch {
+	case validate.IsFieldErrors(err),
+		errors.Is(err, ErrBodyTooLarge),
+		errors.Is(err, ErrUnsupportedMediaType),
+		errors.Is(err, ErrUnsupportedEncoding):
+		return err
+	}
+
+	return validate.NewFieldsError(bodyField, err)
+}
+
+// successStatus picks the status of a successful typed response.
+func successStatus(r *http.Request, resp any) int {
+	switch v := resp.(type) {
+	case StatusCoder:
+		return v.StatusCode()
+	case NoContent, *NoContent:
+		return http.StatusNoContent
+	}
+
+	if r.Method == http.MethodPost {
+		return http.StatusCreated
+	}
+
+	return http.StatusOK
+}
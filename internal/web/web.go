@@ -2,11 +2,18 @@ package web
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // Handler is a type definition that handles a http request within the mini framework.
@@ -16,21 +23,38 @@ type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request) e
 // object for each of our http handlers. Feel free to add any configuration
 // data/logic on this App struct.
 type App struct {
-	mux *chi.Mux
-	shutdown chan os.Signal
-	middlewares []Middleware
+	mux            *chi.Mux
+	otmux          http.Handler
+	shutdown       chan os.Signal
+	middlewares    []Middleware
+	tracer         trace.Tracer
+	preflight      Handler
+	preflightPaths map[string]bool
+	maxBodySize    int64
+	writeTimeout   time.Duration
+	routes         []*Route
 }
 
-// NewApp creates an App instance using the chi router.
-func NewApp(shutdown chan os.Signal, middlewares ...Middleware) *App {
+// NewApp creates an App instance using the chi router. A nil tracer disables
+// the creation of spans.
+func NewApp(shutdown chan os.Signal, tracer trace.Tracer, middlewares ...Middleware) *App {
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer("")
+	}
+
+	mux := chi.NewMux()
 
-	// TO DO: Create an OpenTelemetry HTTP Handler which wraps our router.
-	mux := chi.NewMux();
+	// Create an OpenTelemetry HTTP Handler which wraps our router. This will
+	// start the initial span and annotate it with information about the
+	// request/trusted parts of the response.
+	otmux := otelhttp.NewHandler(mux, "request")
 
 	return &App{
-		mux: mux,
-		shutdown: shutdown,
+		mux:         mux,
+		otmux:       otmux,
+		shutdown:    shutdown,
 		middlewares: middlewares,
+		tracer:      tracer,
 	}
 }
 
@@ -40,37 +64,164 @@ func (a *App) SignalShutdown() {
 	a.shutdown <- syscall.SIGTERM
 }
 
-// ServeHTTP method implements the http.Handler interface for App. 
+// ServeHTTP method implements the http.Handler interface for App.
 // It's the entry point for all http traffic.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	a.mux.ServeHTTP(w, r)
+	a.otmux.ServeHTTP(w, r)
+}
+
+// EnableCORS adds the CORS middleware to the app and registers an OPTIONS
+// route for every path added afterwards, so preflight requests reach the
+// middleware instead of being rejected by the router. It must be called
+// before any route is added.
+func (a *App) EnableCORS(mw Middleware) {
+	a.middlewares = append(a.middlewares, mw)
+
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	a.preflight = wrapMiddleware(a.middlewares, h)
+	a.preflightPaths = make(map[string]bool)
+}
+
+// SetMaxBodySize sets the largest request body, in bytes, that Decode will
+// read for the routes of the app. A value of zero or less restores
+// DefaultMaxBodySize.
+func (a *App) SetMaxBodySize(n int64) {
+	a.maxBodySize = n
+}
+
+// SetWriteTimeout tells the app the write timeout of the server it is
+// served by. Streaming responses extend the write deadline by it whenever
+// they send data, instead of being cut off once it has passed. A value of
+// zero or less restores DefaultWriteTimeout.
+func (a *App) SetWriteTimeout(d time.Duration) {
+	a.writeTimeout = d
 }
 
 // Handle sets a handler function for a given HTTP method and path pair
 // to the application server mux.
-func (a *App) Handle(method string, group string, path string, handler Handler, middlewares ...Middleware) {
-	handler = wrapMiddleware(middlewares, handler)
-	handler = wrapMiddleware(a.middlewares, handler)
+func (a *App) Handle(method string, group string, path string, handler Handler, middlewares ...Middleware) *Route {
+	finalPath := path
+	if group != "" {
+		finalPath = "/" + group + path
+	}
+
+	return a.route(method, finalPath, handler, middlewares)
+}
 
-	a.handle(method, group, path, handler)
+// Group returns a group of routes served under the path prefix. The
+// middlewares run, in order, before the ones of every route of the group.
+func (a *App) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		app:         a,
+		prefix:      prefix,
+		middlewares: middlewares,
+	}
 }
 
 // =============================================================================
 
+// route wraps the handler with the route and app middlewares and binds it to
+// the path. When CORS is enabled an OPTIONS route is added the first time a
+// path is seen. The returned route is recorded so it can be described.
+func (a *App) route(method string, path string, handler Handler, middlewares []Middleware) *Route {
+	rt := Route{
+		Method: method,
+		Path:   path,
+	}
+
+	handler = wrapMiddleware(middlewares, handler)
+	handler = negotiable(handler, &rt)
+	handler = wrapMiddleware(a.middlewares, handler)
+
+	a.handle(method, path, handler)
+
+	if a.preflight != nil && method != http.MethodOptions && !a.preflightPaths[path] {
+		a.preflightPaths[path] = true
+		a.handle(http.MethodOptions, path, a.preflight)
+	}
+
+	a.routes = append(a.routes, &rt)
+
+	return &rt
+}
+
 // handle sets a handler function for a given HTTP method and path pair
 // to the application server mux.
-func (a *App) handle(method string, group string, path string, handler Handler) {
+func (a *App) handle(method string, path string, handler Handler) {
 	h := func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
+		ctx, span := a.startSpan(r)
+		defer span.End()
+
+		v := Values{
+			TraceId:      traceID(r, span.SpanContext()),
+			Tracer:       a.tracer,
+			Now:          time.Now().UTC(),
+			MaxBodySize:  a.maxBodySize,
+			WriteTimeout: a.writeTimeout,
+			Accept:       r.Header.Get("Accept"),
+		}
+
+		w.Header().Set(RequestIDHeader, v.TraceId)
+
+		ctx = setValues(ctx, &v)
+		r = r.WithContext(ctx)
+
+		// Errors should be handled by the error middleware. Anything that
+		// makes it this far is unexpected, so don't leak its details.
 		if err := handler(ctx, w, r); err != nil {
-			w.Write([]byte(err.Error()))
+			if IsShutdown(err) {
+				a.SignalShutdown()
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		}
 	}
 
-	finalPath := path
-	if group != "" {
-		finalPath = "/" + group + path
+	a.mux.MethodFunc(method, path, h)
+}
+
+// negotiable rejects requests whose Accept header can't be satisfied before
+// the handler does any work, and so before it has any side effect. When the
+// route describes its responses, the Accept header must allow one of the
+// media types able to represent all of them. Otherwise a registered encoder
+// must satisfy it. It runs inside the app middleware so the rejection is
+// logged and rendered like any other error.
+func negotiable(handler Handler, rt *Route) Handler {
+	var once sync.Once
+	var offers []string
+
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		// The route is described after it is bound, so its offers are only
+		// known once requests are served.
+		once.Do(func() {
+			offers = rt.offers()
+		})
+
+		accept := r.Header.Get("Accept")
+
+		switch {
+		case offers == nil:
+			if !acceptable(accept) {
+				return fmt.Errorf("%w: %s", ErrNotAcceptable, accept)
+			}
+		default:
+			if Negotiate(r, offers...) == "" {
+				return fmt.Errorf("%w: %s", ErrNotAcceptable, accept)
+			}
+		}
+
+		return handler(ctx, w, r)
 	}
+}
+
+// startSpan initializes the request by adding a span and writing otel
+// related information into the response writer for the response.
+func (a *App) startSpan(r *http.Request) (context.Context, trace.Span) {
+	ctx, span := a.tracer.Start(r.Context(), "internal.web.handle")
+	span.SetAttributes(attribute.String("endpoint", r.RequestURI))
 
-	a.mux.MethodFunc(method, finalPath, h)
-}
\ No newline at end of file
+	return ctx, span
+}
@@ -0,0 +1,350 @@
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hpetrov29/restapi/internal/validate"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// xmlNode is an element of an XML document.
+type xmlNode struct {
+	name     string
+	text     string
+	children []*xmlNode
+}
+
+// encodeXML converts the value into an XML document built from its JSON
+// representation, so both formats use the same names and leave out the same
+// fields. Objects become elements named after their keys, array elements are
+// written as item elements and null members are left out. Slices are wrapped
+// in an items element so the document has a single root, any other value in
+// an item element.
+func encodeXML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		var ute *json.UnsupportedTypeError
+		if errors.As(err, &ute) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedValue, err)
+		}
+		return nil, err
+	}
+
+	root := xml.StartElement{Name: xml.Name{Local: "item"}}
+	if data[0] == '[' {
+		root.Name.Local = "items"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	switch string(data) {
+	case "null":
+		err = enc.EncodeElement("", root)
+	default:
+		err = xmlElement(dec, enc, root)
+	}
+
+	if err == nil {
+		err = enc.Flush()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// xmlElement reads the next JSON value from the decoder and writes it as an
+// element starting with start.
+func xmlElement(dec *json.Decoder, enc *xml.Encoder, start xml.StartElement) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		if tok == nil {
+			return nil
+		}
+		return enc.EncodeElement(fmt.Sprint(tok), start)
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		child := xml.StartElement{Name: xml.Name{Local: "item"}}
+
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			name, _ := key.(string)
+			if !xmlName(name) {
+				return fmt.Errorf("%w: %q is not a valid XML element name", ErrUnsupportedValue, name)
+			}
+			child.Name.Local = name
+		}
+
+		if err := xmlElement(dec, enc, child); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing delimiter of the object or array.
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName reports whether the name can be used as an element name.
+func xmlName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// =============================================================================
+
+// decodeXML converts the XML document into the JSON representation of the
+// value and decodes that, so an XML body is accepted exactly when the same
+// body would be accepted as JSON and unknown elements are rejected. The type
+// of the value tells whether an element holds an object, an array or a
+// scalar, the name of the root element is ignored.
+func decodeXML(data []byte, v any) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	root, err := readXMLDocument(dec)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return validate.NewFieldsError(bodyField, errors.New("body must not be empty"))
+		}
+		return validate.NewFieldsError(bodyField, fmt.Errorf("malformed XML at offset %d: %s", dec.InputOffset(), strings.TrimPrefix(err.Error(), "XML syntax error: ")))
+	}
+
+	for {
+		end := dec.InputOffset()
+
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if cd, ok := tok.(xml.CharData); ok && len(bytes.TrimSpace(cd)) == 0 {
+			continue
+		}
+		if _, ok := tok.(xml.Comment); ok {
+			continue
+		}
+
+		return validate.NewFieldsError(bodyField, fmt.Errorf("unexpected data after XML document ending at offset %d", end))
+	}
+
+	var buf bytes.Buffer
+	writeXMLAsJSON(&buf, root, reflect.TypeOf(v))
+
+	jsonDec := json.NewDecoder(&buf)
+	jsonDec.DisallowUnknownFields()
+	if err := jsonDec.Decode(v); err != nil {
+		// The offsets of the JSON decoder point into the converted document,
+		// which the client never saw.
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return validate.NewFieldsError(typeErr.Field, fmt.Errorf("expected %s but got %s", jsonType(typeErr.Type), typeErr.Value))
+		}
+		return decodeError(err)
+	}
+
+	return nil
+}
+
+// readXMLDocument reads the root element of the document. It returns io.EOF
+// when the document has no root element.
+func readXMLDocument(dec *xml.Decoder) (*xmlNode, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			return readXMLElement(dec, tok)
+
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) > 0 {
+				return nil, errors.New("text outside of the root element")
+			}
+		}
+	}
+}
+
+// readXMLElement reads the content of the element up to its end tag.
+func readXMLElement(dec *xml.Decoder, start xml.StartElement) (*xmlNode, error) {
+	n := xmlNode{name: start.Name.Local}
+
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, err := readXMLElement(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+
+		case xml.CharData:
+			text.Write(tok)
+
+		case xml.EndElement:
+			n.text = text.String()
+			return &n, nil
+		}
+	}
+}
+
+// writeXMLAsJSON writes the element as the JSON representation of a value of
+// the type. Text that isn't a valid representation of the type is written as
+// a string so decoding it reports the mismatch, it is never copied into the
+// JSON document as is.
+func writeXMLAsJSON(buf *bytes.Buffer, n *xmlNode, t reflect.Type) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() == reflect.Interface {
+		if len(n.children) == 0 {
+			writeJSONString(buf, n.text)
+			return
+		}
+		writeXMLObject(buf, n, func(string) reflect.Type { return t })
+		return
+	}
+
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		writeJSONString(buf, n.text)
+		return
+	}
+
+	text := strings.TrimSpace(n.text)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if len(n.children) == 0 && text != "" {
+			writeJSONString(buf, n.text)
+			return
+		}
+
+		fields := make(map[string]reflect.Type)
+		for _, col := range csvColumns(t) {
+			fields[col.name] = t.FieldByIndex(col.index).Type
+		}
+
+		writeXMLObject(buf, n, func(name string) reflect.Type { return fields[name] })
+
+	case reflect.Map:
+		if len(n.children) == 0 && text != "" {
+			writeJSONString(buf, n.text)
+			return
+		}
+
+		writeXMLObject(buf, n, func(string) reflect.Type { return t.Elem() })
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 || (len(n.children) == 0 && text != "") {
+			writeJSONString(buf, n.text)
+			return
+		}
+
+		buf.WriteByte('[')
+		for i, child := range n.children {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeXMLAsJSON(buf, child, t.Elem())
+		}
+		buf.WriteByte(']')
+
+	case reflect.Bool:
+		if text != "true" && text != "false" {
+			writeJSONString(buf, n.text)
+			return
+		}
+		buf.WriteString(text)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(text, 64); err != nil || !json.Valid([]byte(text)) {
+			writeJSONString(buf, n.text)
+			return
+		}
+		buf.WriteString(text)
+
+	default:
+		writeJSONString(buf, n.text)
+	}
+}
+
+// writeXMLObject writes the children of the element as the members of a JSON
+// object, field returns the type of the member with the name.
+func writeXMLObject(buf *bytes.Buffer, n *xmlNode, field func(name string) reflect.Type) {
+	buf.WriteByte('{')
+	for i, child := range n.children {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(buf, child.name)
+		buf.WriteByte(':')
+		writeXMLAsJSON(buf, child, field(child.name))
+	}
+	buf.WriteByte('}')
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"strings"
+	"testing"
+)
+
+type xmlTestUser struct {
+	ID     string   `json:"id"`
+	Secret []byte   `json:"-"`
+	Roles  []string `json:"roles"`
+	Age    int      `json:"age,omitempty"`
+	Note   *string  `json:"note,omitempty"`
+}
+
+func TestEncodeXML(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"struct", xmlTestUser{ID: "a", Secret: []byte("secret"), Roles: []string{"ADMIN"}, Age: 3}, `<item><id>a</id><roles><item>ADMIN</item></roles><age>3</age></item>`},
+		{"slice", []xmlTestUser{{ID: "a"}, {ID: "b"}}, `<items><item><id>a</id></item><item><id>b</id></item></items>`},
+		{"escaped", xmlTestUser{ID: "<a&b>"}, `<item><id>&lt;a&amp;b&gt;</id></item>`},
+		{"map", map[string]int{"b": 2, "a": 1}, `<item><a>1</a><b>2</b></item>`},
+		{"null", nil, `<item></item>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeXML(tt.v)
+			if err != nil {
+				t.Fatalf("encodeXML() error = %v", err)
+			}
+
+			if got := strings.TrimPrefix(string(data), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"); got != tt.want {
+				t.Errorf("encodeXML() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeXML(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantErr   string
+		wantID    string
+		wantRoles int
+	}{
+		{"valid", `<user><id>a</id><roles><item>ADMIN</item><item>USER</item></roles><age>3</age></user>`, "", "a", 2},
+		{"whitespace and comments", "<?xml version=\"1.0\"?>\n<user>\n  <id>a</id>\n</user>\n<!-- end -->\n", "", "a", 0},
+		{"unknown element", `<user><id>a</id><Secret>c2VjcmV0</Secret></user>`, "unknown field", "", 0},
+		{"number as text", `<user><age>three</age></user>`, "expected integer but got string", "", 0},
+		{"no json injection", `<user><age>1, "id": "b"</age></user>`, "expected integer but got string", "", 0},
+		{"empty", ``, "body must not be empty", "", 0},
+		{"mismatched tags", `<user><id>a</user>`, "malformed XML", "", 0},
+		{"trailing data", `<user></user><user></user>`, "unexpected data after XML document", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var usr xmlTestUser
+			err := decodeXML([]byte(tt.body), &usr)
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("decodeXML() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("decodeXML() error = %v, want %q", err, tt.wantErr)
+			case tt.wantErr != "":
+				return
+			}
+
+			if usr.ID != tt.wantID || len(usr.Roles) != tt.wantRoles {
+				t.Errorf("decodeXML() = %+v, want id %q and %d roles", usr, tt.wantID, tt.wantRoles)
+			}
+		})
+	}
+}